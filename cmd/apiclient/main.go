@@ -0,0 +1,81 @@
+// ==========================
+// cmd/apiclient/main.go
+// ==========================
+package main
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+const usage = `Usage: apiclient <command>
+
+Commands:
+  create <name> <scope,...>  register a client and print its API key
+  list                       list clients and their scopes
+  revoke <id>                disable a client's key`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+	if cfg.DatabaseURL == "" {
+		log.Fatal("DATABASE_URL is not set")
+	}
+
+	db, err := storage.OpenPostgres(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	clients := services.NewAPIClientService(cfg, storage.NewPostgresAPIClientStore(db))
+
+	switch {
+	case os.Args[1] == "create" && len(os.Args) == 4:
+		client, key, err := clients.Create(ctx, models.APIClientRequest{
+			Name:   os.Args[2],
+			Scopes: strings.Split(os.Args[3], ","),
+		}, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Client: %s\nAPI key (shown once): %s\n", client.ID, key)
+
+	case os.Args[1] == "list" && len(os.Args) == 2:
+		list, err := clients.List(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, client := range list {
+			state := "active"
+			if client.RevokedAt != nil {
+				state = "revoked"
+			}
+			fmt.Printf("%s  %-20s  %s...  %-7s  %v\n", client.ID, client.Name, client.KeyPrefix, state, client.Scopes)
+		}
+
+	case os.Args[1] == "revoke" && len(os.Args) == 3:
+		if err := clients.Revoke(ctx, os.Args[2]); err != nil {
+			log.Fatal(err)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
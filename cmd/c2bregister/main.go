@@ -0,0 +1,40 @@
+// ==========================
+// cmd/c2bregister/main.go
+// ==========================
+package main
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/daraja"
+	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
+	"context"
+	"log"
+)
+
+// Registers C2B_VALIDATION_URL and C2B_CONFIRMATION_URL with Daraja for
+// BUSINESS_SHORT_CODE. Run once per environment, or whenever the URLs change.
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
+	if cfg.C2BValidationURL == "" || cfg.C2BConfirmationURL == "" {
+		log.Fatal("C2B_VALIDATION_URL and C2B_CONFIRMATION_URL must be set")
+	}
+
+	httpClient := daraja.NewHTTPClient(cfg)
+	authService := services.NewAuthService(cfg, httpClient, storage.NewMemoryTokenStore(), storage.NewMemoryLocker())
+	c2bService := services.NewC2BService(cfg, daraja.NewClient(cfg, authService, httpClient))
+
+	resp, err := c2bService.RegisterURLs(context.Background())
+	if err != nil {
+		log.Fatal("Failed to register C2B URLs:", err)
+	}
+
+	log.Printf("C2B URLs registered for shortcode %d: %s (%s)",
+		cfg.BusinessShortCode, resp.ResponseDescription, resp.ResponseCode)
+	log.Printf("Validation URL: %s", cfg.C2BValidationURL)
+	log.Printf("Confirmation URL: %s", cfg.C2BConfirmationURL)
+}
@@ -0,0 +1,81 @@
+// ==========================
+// cmd/migrate/main.go
+// ==========================
+package main
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/storage"
+	"context"
+	"fmt"
+	"log"
+	"os"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up      apply all pending migrations
+  status  list migrations and whether they are applied
+  verify  exit non-zero if the database is not at the embedded schema`
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+	if cfg.DatabaseURL == "" {
+		log.Fatal("DATABASE_URL is not set")
+	}
+
+	db, err := storage.OpenPostgres(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator := storage.NewMigrator(db)
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := migrator.Apply(ctx)
+		for _, m := range applied {
+			log.Printf("Applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			log.Println("Database is up to date")
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			appliedAt := "-"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s  %-8s  %s\n", s.Version, s.Name, s.State, appliedAt)
+		}
+
+	case "verify":
+		if err := migrator.Verify(ctx); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Database schema matches embedded migrations")
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -5,25 +5,20 @@ package main
 
 import (
 	"awesomeProject/internal/config"
+	"awesomeProject/internal/daraja"
 	"awesomeProject/internal/handlers"
+	"awesomeProject/internal/middleware"
+	"awesomeProject/internal/models"
 	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
 	ws "awesomeProject/internal/websocket"
 	"fmt"
 	"log"
-	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // In production, validate origin properly
-	},
-}
-
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -31,28 +26,113 @@ func main() {
 		log.Fatal("Failed to load configuration:", err)
 	}
 
+	// Initialize storage
+	store, err := storage.Open(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+	defer store.Close()
+	repo := store.Transactions
+
 	// Initialize services
-	authService := services.NewAuthService(cfg)
-	b2cService := services.NewB2CService(cfg, authService)
-	hub := ws.NewHub()
+	httpClient := daraja.NewHTTPClient(cfg)
+	authService := services.NewAuthService(cfg, httpClient, store.Tokens, store.Locks)
+	darajaClient := daraja.NewClient(cfg, authService, httpClient)
+	callbackTokens := services.NewCallbackTokens(cfg.CallbackTokenSecret)
+	stkService := services.NewSTKService(cfg, darajaClient, callbackTokens)
+	b2cService := services.NewB2CService(cfg, darajaClient, callbackTokens)
+	b2bService := services.NewB2BService(cfg, darajaClient, callbackTokens)
+	statusService := services.NewTransactionStatusService(cfg, darajaClient, callbackTokens)
+	balanceService := services.NewAccountBalanceService(cfg, darajaClient, store.Balances)
+	reversalService := services.NewReversalService(cfg, darajaClient, callbackTokens)
+	stkReconciler := services.NewSTKReconciler(cfg, stkService, repo, store.Locks)
+	webhookService := services.NewWebhookService(cfg, store.Webhooks)
+	clientService := services.NewAPIClientService(cfg, store.APIClients)
+	payoutApprovals := services.NewPayoutApprovals(cfg, repo, store.APIClients)
+	var backplane ws.Backplane = ws.NewMemoryBackplane()
+	var events ws.EventLog = ws.NewMemoryEventLog(cfg.WSReplayBuffer)
+	if client := store.Redis(); client != nil {
+		backplane = ws.NewRedisBackplane(client)
+		events = ws.NewRedisEventLog(client, cfg.WSReplayBuffer)
+	}
+	hub := ws.NewHub(backplane, events)
+	hub.OnBroadcast(webhookService.Notify)
 	go hub.Run()
 
+	c2bRules, err := services.C2BRulesFromConfig(cfg)
+	if err != nil {
+		log.Fatal("Failed to load C2B validation rules:", err)
+	}
+
+	b2cRules, err := services.PayoutRulesFromConfig("B2C", cfg.B2CLimits, repo, store.Locks)
+	if err != nil {
+		log.Fatal("Failed to load B2C payout rules:", err)
+	}
+	stkRules, err := services.PayoutRulesFromConfig("STK", cfg.STKLimits, repo, store.Locks)
+	if err != nil {
+		log.Fatal("Failed to load STK payout rules:", err)
+	}
+
+	// Only Safaricom, on URLs it was given, may post callbacks
+	callbackGuard, err := middleware.NewCallbackGuard(cfg.CallbackAllowedCIDRs, callbackTokens)
+	if err != nil {
+		log.Fatal("Failed to load callback guard:", err)
+	}
+	fromDaraja := callbackGuard.Source()
+	forTransaction := callbackGuard.Transaction()
+
+	// Everything else under /api/v1 needs an API key with the route's scope
+	if cfg.AdminAPIKey == "" {
+		log.Println("Warning: ADMIN_API_KEY not set, API clients can only be created with cmd/apiclient")
+	}
+	auth := middleware.NewAPIKeyAuth(clientService)
+
 	// Initialize handlers
-	stkHandler := handlers.NewSTKHandler(cfg, authService, hub)
-	b2cHandler := handlers.NewB2CHandler(cfg, b2cService, hub)
+	stkHandler := handlers.NewSTKHandler(cfg, stkService, stkRules, repo, hub)
+	b2cHandler := handlers.NewB2CHandler(cfg, b2cService, balanceService, payoutApprovals, b2cRules, repo, store.Audit, hub)
+	b2bHandler := handlers.NewB2BHandler(cfg, b2bService, repo, hub)
+	c2bHandler := handlers.NewC2BHandler(cfg, c2bRules, repo, hub)
+	statusHandler := handlers.NewTransactionStatusHandler(cfg, statusService, repo, hub)
+	balanceHandler := handlers.NewBalanceHandler(cfg, balanceService)
+	reversalHandler := handlers.NewReversalHandler(cfg, reversalService, statusService, repo, hub)
+	webhookHandler := handlers.NewWebhookHandler(cfg, webhookService, store.Webhooks)
+	clientHandler := handlers.NewAPIClientHandler(cfg, clientService)
+	wsHandler := handlers.NewWSHandler(cfg, hub, ws.NewTokenSigner(cfg.WSTokenSecret, time.Duration(cfg.WSTokenTTL)*time.Second), repo)
+
+	// Query Daraja for STK Pushes whose callback never arrives
+	go stkReconciler.Run(stkHandler.ApplyCallback)
+
+	// Keep the balance snapshot fresh if BALANCE_REFRESH_INTERVAL is set
+	go balanceService.Run()
+
+	// Send queued webhooks and retry failed ones
+	go webhookService.Run()
 
 	// Setup Gin router
 	if !cfg.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	r := gin.Default()
+	// gin.Default's logger would write websocket and SSE tokens to the log
+	r := gin.New()
+	r.Use(middleware.AccessLog(), gin.Recovery())
+
+	// Forwarding headers are only believed from these, see CallbackGuard
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	if len(cfg.ClientIPHeaders) > 0 {
+		r.RemoteIPHeaders = cfg.ClientIPHeaders
+	}
+	if len(cfg.CallbackAllowedCIDRs) > 0 && len(cfg.TrustedProxies) == 0 {
+		log.Println("Warning: CALLBACK_ALLOWED_CIDRS set without TRUSTED_PROXIES, callbacks are checked against the address of the proxy in front of the app")
+	}
 
 	// CORS middleware
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Idempotency-Key, Authorization, X-API-Key, Last-Event-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -62,34 +142,99 @@ func main() {
 		c.Next()
 	})
 
-	// WebSocket endpoint
-	r.GET("/ws/payments", func(c *gin.Context) {
-		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-		if err != nil {
-			log.Println("WebSocket upgrade error:", err)
-			return
-		}
-
-		client := ws.NewClient(hub, conn)
-		hub.Register <- client
-
-		go client.WritePump()
-		go client.ReadPump()
-	})
+	// WebSocket endpoint, authenticated with a token from /api/v1/ws/token
+	r.GET("/ws/payments", wsHandler.Connect)
+	r.POST("/api/v1/ws/token", auth.Require(models.ScopeReadTransactions), wsHandler.IssueToken)
+	r.GET("/api/v1/events", wsHandler.StreamEvents)
+	r.GET("/api/v1/events/schema", wsHandler.EventSchema)
 
 	// STK Push routes
 	stk := r.Group("/api/v1/stk")
 	{
-		stk.POST("/initiate", stkHandler.InitiateSTKPush)
-		stk.POST("/callback", stkHandler.STKPushCallback)
+		stk.POST("/initiate", auth.Require(models.ScopeSTKInitiate), middleware.Idempotency(store.Idempotency, "stk_initiate"), stkHandler.InitiateSTKPush)
+		stk.POST("/callback", forTransaction, stkHandler.STKPushCallback)
+		stk.POST("/callback/:token", forTransaction, stkHandler.STKPushCallback)
+		stk.GET("/status/:checkoutRequestID", auth.Require(models.ScopeReadTransactions), stkHandler.QueryStatus)
 	}
 
 	// B2C routes
 	b2c := r.Group("/api/v1/b2c")
 	{
-		b2c.POST("/payment", b2cHandler.InitiatePayment)
-		b2c.POST("/result", b2cHandler.HandleCallback)
-		b2c.POST("/timeout", b2cHandler.HandleTimeout)
+		b2c.POST("/payment", auth.Require(models.ScopeB2CPay), middleware.Idempotency(store.Idempotency, "b2c_payment"), b2cHandler.InitiatePayment)
+		b2c.POST("/result", forTransaction, b2cHandler.HandleCallback)
+		b2c.POST("/result/:token", forTransaction, b2cHandler.HandleCallback)
+		b2c.POST("/timeout", forTransaction, b2cHandler.HandleTimeout)
+		b2c.POST("/timeout/:token", forTransaction, b2cHandler.HandleTimeout)
+		b2c.GET("/approvals", auth.Require(models.ScopeB2CApprove), b2cHandler.ListApprovals)
+		b2c.GET("/approvals/:id", auth.Require(models.ScopeB2CApprove), b2cHandler.GetApproval)
+		b2c.POST("/approvals/:id", auth.Require(models.ScopeB2CApprove), b2cHandler.DecideApproval)
+	}
+
+	// B2B routes
+	b2b := r.Group("/api/v1/b2b")
+	{
+		b2b.POST("/payment", auth.Require(models.ScopeB2BPay), middleware.Idempotency(store.Idempotency, "b2b_payment"), b2bHandler.InitiatePayment)
+		b2b.POST("/result", forTransaction, b2bHandler.HandleCallback)
+		b2b.POST("/result/:token", forTransaction, b2bHandler.HandleCallback)
+		b2b.POST("/timeout", forTransaction, b2bHandler.HandleTimeout)
+		b2b.POST("/timeout/:token", forTransaction, b2bHandler.HandleTimeout)
+	}
+
+	// C2B routes (URLs are registered with cmd/c2bregister)
+	c2b := r.Group("/api/v1/c2b")
+	{
+		c2b.POST("/validation", fromDaraja, c2bHandler.HandleValidation)
+		c2b.POST("/confirmation", fromDaraja, c2bHandler.HandleConfirmation)
+	}
+
+	// Transaction status routes
+	transactions := r.Group("/api/v1/transactions")
+	{
+		transactions.POST("/status", auth.Require(models.ScopeReadTransactions), statusHandler.QueryStatus)
+		transactions.POST("/status/result", forTransaction, statusHandler.HandleResult)
+		transactions.POST("/status/result/:token", forTransaction, statusHandler.HandleResult)
+		transactions.POST("/status/timeout", forTransaction, statusHandler.HandleTimeout)
+		transactions.POST("/status/timeout/:token", forTransaction, statusHandler.HandleTimeout)
+	}
+
+	// Account balance routes
+	balance := r.Group("/api/v1/balance")
+	{
+		balance.GET("", auth.Require(models.ScopeReadBalance), balanceHandler.GetBalance)
+		balance.POST("/refresh", auth.Require(models.ScopeReadBalance), balanceHandler.RefreshBalance)
+		balance.POST("/result", fromDaraja, balanceHandler.HandleResult)
+		balance.POST("/timeout", fromDaraja, balanceHandler.HandleTimeout)
+	}
+
+	// Reversal routes
+	reversals := r.Group("/api/v1/reversals")
+	{
+		reversals.POST("", auth.Require(models.ScopeReversalInitiate), reversalHandler.InitiateReversal)
+		reversals.POST("/result", forTransaction, reversalHandler.HandleResult)
+		reversals.POST("/result/:token", forTransaction, reversalHandler.HandleResult)
+		reversals.POST("/timeout", forTransaction, reversalHandler.HandleTimeout)
+		reversals.POST("/timeout/:token", forTransaction, reversalHandler.HandleTimeout)
+	}
+
+	// Webhook routes
+	webhooks := r.Group("/api/v1/webhooks", auth.Require(models.ScopeWebhooksManage))
+	{
+		webhooks.POST("/endpoints", webhookHandler.CreateEndpoint)
+		webhooks.GET("/endpoints", webhookHandler.ListEndpoints)
+		webhooks.DELETE("/endpoints/:id", webhookHandler.DeleteEndpoint)
+		webhooks.GET("/deliveries", webhookHandler.ListDeliveries)
+		webhooks.GET("/deliveries/:id", webhookHandler.GetDelivery)
+		webhooks.POST("/deliveries/:id/redeliver", webhookHandler.Redeliver)
+		webhooks.GET("/dead-letters", webhookHandler.ListDeadLetters)
+	}
+
+	// API client routes
+	clients := r.Group("/api/v1/clients", auth.Require(models.ScopeClientsManage))
+	{
+		clients.POST("", clientHandler.CreateClient)
+		clients.GET("", clientHandler.ListClients)
+		clients.GET("/:id", clientHandler.GetClient)
+		clients.DELETE("/:id", clientHandler.RevokeClient)
 	}
 
 	// Health check
@@ -107,6 +252,7 @@ func main() {
 	log.Printf("B2C Payment endpoint: POST http://%s/b2c/payment", addr)
 	log.Printf("B2C Result callback: POST http://%s/b2c/result", addr)
 	log.Printf("B2C Timeout callback: POST http://%s/b2c/timeout", addr)
+	log.Printf("B2B Payment endpoint: POST http://%s/api/v1/b2b/payment", addr)
 
 	if err := r.Run(addr); err != nil {
 		log.Fatal("Failed to start server:", err)
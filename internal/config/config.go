@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -34,12 +35,38 @@ type Config struct {
 	B2BResultURL    string
 	B2BTimeoutURL   string
 
+	TransactionStatusResultURL  string
+	TransactionStatusTimeoutURL string
+
+	AccountBalanceResultURL  string
+	AccountBalanceTimeoutURL string
+
+	ReversalResultURL  string
+	ReversalTimeoutURL string
+
+	// C2B
+	C2BValidationURL   string
+	C2BConfirmationURL string
+	C2BResponseType    string // What M-Pesa does when validation is unreachable: Completed or Cancelled
+	C2BAccountPattern  string // Regular expression bill reference numbers must match
+	C2BMinAmount       float64
+	C2BMaxAmount       float64
+
 	// Server Configuration
 	Host   string
 	Port   int
 	Debug  bool
 	Reload bool
 
+	// Callback authenticity
+	CallbackAllowedCIDRs []string // Safaricom gateway addresses callbacks may come from; empty allows any
+	CallbackTokenSecret  string   // Signs the per-transaction tokens in callback URLs
+	TrustedProxies       []string // Proxies whose client IP headers are believed
+	ClientIPHeaders      []string // Headers a trusted proxy puts the client IP in, e.g. Fly-Client-IP
+
+	// API authentication
+	AdminAPIKey string // Break-glass key limited to clients:manage, for bootstrapping API clients
+
 	// Database
 	DatabaseURL string
 
@@ -51,6 +78,47 @@ type Config struct {
 
 	// API Timeout
 	APITimeout int
+
+	// Account balance
+	BalanceRefreshInterval int    // Seconds between scheduled balance queries, 0 disables
+	BalanceMaxAge          int    // Seconds a snapshot is trusted for rejecting payouts
+	PayoutAccount          string // Account B2C payouts are drawn from
+
+	// B2C maker-checker: payouts held until a second API client approves them
+	B2CApprovalThreshold    int  // Amounts above this need approval, 0 disables
+	B2CApproveNewRecipients bool // Payouts to numbers never paid before need approval
+	B2CApprovalTTL          int  // Seconds a held payout can still be approved
+
+	// Limits checked before money is requested or paid out
+	B2CLimits PayoutLimits
+	STKLimits PayoutLimits
+
+	// STK reconciliation (seconds)
+	STKReconcileAfter      int
+	STKReconcileBackoff    int
+	STKReconcileMaxBackoff int
+	STKReconcileAttempts   int
+
+	// WebSocket authentication
+	WSTokenSecret    string   // Signs connection tokens; shared by every instance
+	WSTokenTTL       int      // Seconds a connection token is valid for
+	WSAllowedOrigins []string // Browser origins allowed to connect, "*" for any
+	WSReplayBuffer   int      // Events kept for clients resuming after a disconnect
+
+	// Outbound webhooks (seconds)
+	WebhookTimeout      int
+	WebhookRetryBackoff int // Doubles after every failed attempt
+	WebhookMaxBackoff   int
+	WebhookMaxAttempts  int // Then the delivery is dead-lettered
+}
+
+// PayoutLimits configures the payout rules of one transaction type. Zero or
+// empty disables a rule.
+type PayoutLimits struct {
+	RecipientDailyCap int      // Total per phone number over the last 24 hours; for STK, the payer
+	ClientHourlyCap   int      // Total per API client over the last hour
+	CommandMax        []string // CommandID=amount, the largest single payment per CommandID
+	BlockedMSISDNs    []string
 }
 
 func (c *Config) OAuthURL() string {
@@ -69,6 +137,26 @@ func (c *Config) B2CURL() string {
 	return fmt.Sprintf("%s/mpesa/b2c/v3/paymentrequest", c.BaseURL)
 }
 
+func (c *Config) B2BURL() string {
+	return fmt.Sprintf("%s/mpesa/b2b/v1/paymentrequest", c.BaseURL)
+}
+
+func (c *Config) C2BRegisterURL() string {
+	return fmt.Sprintf("%s/mpesa/c2b/v1/registerurl", c.BaseURL)
+}
+
+func (c *Config) TransactionStatusURL() string {
+	return fmt.Sprintf("%s/mpesa/transactionstatus/v1/query", c.BaseURL)
+}
+
+func (c *Config) AccountBalanceURL() string {
+	return fmt.Sprintf("%s/mpesa/accountbalance/v1/query", c.BaseURL)
+}
+
+func (c *Config) ReversalURL() string {
+	return fmt.Sprintf("%s/mpesa/reversal/v1/request", c.BaseURL)
+}
+
 func Load() (*Config, error) {
 	// Load .env file
 	_ = godotenv.Load()
@@ -80,6 +168,22 @@ func Load() (*Config, error) {
 
 	port, _ := strconv.Atoi(getEnv("PORT", "8000"))
 	apiTimeout, _ := strconv.Atoi(getEnv("API_TIMEOUT", "30"))
+	reconcileAfter, _ := strconv.Atoi(getEnv("STK_RECONCILE_AFTER", "90"))
+	reconcileBackoff, _ := strconv.Atoi(getEnv("STK_RECONCILE_BACKOFF", "15"))
+	reconcileMaxBackoff, _ := strconv.Atoi(getEnv("STK_RECONCILE_MAX_BACKOFF", "300"))
+	reconcileAttempts, _ := strconv.Atoi(getEnv("STK_RECONCILE_ATTEMPTS", "10"))
+	balanceRefreshInterval, _ := strconv.Atoi(getEnv("BALANCE_REFRESH_INTERVAL", "0"))
+	balanceMaxAge, _ := strconv.Atoi(getEnv("BALANCE_MAX_AGE", "900"))
+	c2bMinAmount, _ := strconv.ParseFloat(getEnv("C2B_MIN_AMOUNT", "0"), 64)
+	c2bMaxAmount, _ := strconv.ParseFloat(getEnv("C2B_MAX_AMOUNT", "0"), 64)
+	wsTokenTTL, _ := strconv.Atoi(getEnv("WS_TOKEN_TTL", "300"))
+	wsReplayBuffer, _ := strconv.Atoi(getEnv("WS_REPLAY_BUFFER", "1000"))
+	b2cApprovalThreshold, _ := strconv.Atoi(getEnv("B2C_APPROVAL_THRESHOLD", "0"))
+	b2cApprovalTTL, _ := strconv.Atoi(getEnv("B2C_APPROVAL_TTL", "86400"))
+	webhookTimeout, _ := strconv.Atoi(getEnv("WEBHOOK_TIMEOUT", "10"))
+	webhookRetryBackoff, _ := strconv.Atoi(getEnv("WEBHOOK_RETRY_BACKOFF", "30"))
+	webhookMaxBackoff, _ := strconv.Atoi(getEnv("WEBHOOK_MAX_BACKOFF", "3600"))
+	webhookMaxAttempts, _ := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "10"))
 
 	return &Config{
 		ConsumerKey:       getEnv("CONSUMER_KEY", ""),
@@ -103,6 +207,53 @@ func Load() (*Config, error) {
 		RedisURL:          getEnv("REDIS_URL", ""),
 		LogLevel:          getEnv("LOG_LEVEL", "INFO"),
 		APITimeout:        apiTimeout,
+
+		TransactionStatusResultURL:  getEnv("TRANSACTION_STATUS_RESULT_URL", ""),
+		TransactionStatusTimeoutURL: getEnv("TRANSACTION_STATUS_TIMEOUT_URL", ""),
+		AccountBalanceResultURL:     getEnv("ACCOUNT_BALANCE_RESULT_URL", ""),
+		AccountBalanceTimeoutURL:    getEnv("ACCOUNT_BALANCE_TIMEOUT_URL", ""),
+		ReversalResultURL:           getEnv("REVERSAL_RESULT_URL", ""),
+		ReversalTimeoutURL:          getEnv("REVERSAL_TIMEOUT_URL", ""),
+
+		C2BValidationURL:   getEnv("C2B_VALIDATION_URL", ""),
+		C2BConfirmationURL: getEnv("C2B_CONFIRMATION_URL", ""),
+		C2BResponseType:    getEnv("C2B_RESPONSE_TYPE", "Completed"),
+		C2BAccountPattern:  getEnv("C2B_ACCOUNT_PATTERN", ""),
+		C2BMinAmount:       c2bMinAmount,
+		C2BMaxAmount:       c2bMaxAmount,
+
+		CallbackAllowedCIDRs: getEnvList("CALLBACK_ALLOWED_CIDRS"),
+		CallbackTokenSecret:  getEnv("CALLBACK_TOKEN_SECRET", ""),
+		TrustedProxies:       getEnvList("TRUSTED_PROXIES"),
+		ClientIPHeaders:      getEnvList("CLIENT_IP_HEADERS"),
+
+		AdminAPIKey: getEnv("ADMIN_API_KEY", ""),
+
+		BalanceRefreshInterval: balanceRefreshInterval,
+		BalanceMaxAge:          balanceMaxAge,
+		PayoutAccount:          getEnv("PAYOUT_ACCOUNT", "Utility Account"),
+
+		B2CApprovalThreshold:    b2cApprovalThreshold,
+		B2CApproveNewRecipients: getEnv("B2C_APPROVE_NEW_RECIPIENTS", "false") == "true",
+		B2CApprovalTTL:          b2cApprovalTTL,
+
+		B2CLimits: loadPayoutLimits("B2C"),
+		STKLimits: loadPayoutLimits("STK"),
+
+		STKReconcileAfter:      reconcileAfter,
+		STKReconcileBackoff:    reconcileBackoff,
+		STKReconcileMaxBackoff: reconcileMaxBackoff,
+		STKReconcileAttempts:   reconcileAttempts,
+
+		WSTokenSecret:    getEnv("WS_TOKEN_SECRET", ""),
+		WSTokenTTL:       wsTokenTTL,
+		WSAllowedOrigins: getEnvList("WS_ALLOWED_ORIGINS"),
+		WSReplayBuffer:   wsReplayBuffer,
+
+		WebhookTimeout:      webhookTimeout,
+		WebhookRetryBackoff: webhookRetryBackoff,
+		WebhookMaxBackoff:   webhookMaxBackoff,
+		WebhookMaxAttempts:  webhookMaxAttempts,
 	}, nil
 }
 
@@ -112,3 +263,27 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// loadPayoutLimits reads the PREFIX_RECIPIENT_DAILY_CAP, PREFIX_CLIENT_HOURLY_CAP,
+// PREFIX_COMMAND_MAX and PREFIX_BLOCKED_MSISDNS variables
+func loadPayoutLimits(prefix string) PayoutLimits {
+	recipientDailyCap, _ := strconv.Atoi(getEnv(prefix+"_RECIPIENT_DAILY_CAP", "0"))
+	clientHourlyCap, _ := strconv.Atoi(getEnv(prefix+"_CLIENT_HOURLY_CAP", "0"))
+	return PayoutLimits{
+		RecipientDailyCap: recipientDailyCap,
+		ClientHourlyCap:   clientHourlyCap,
+		CommandMax:        getEnvList(prefix + "_COMMAND_MAX"),
+		BlockedMSISDNs:    getEnvList(prefix + "_BLOCKED_MSISDNS"),
+	}
+}
+
+// getEnvList reads a comma-separated list, ignoring empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
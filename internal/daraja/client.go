@@ -0,0 +1,181 @@
+// ==========================
+// internal/daraja/client.go
+// ==========================
+package daraja
+
+import (
+	"awesomeProject/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// TokenSource hands out OAuth access tokens for Daraja
+type TokenSource interface {
+	GetAccessToken(forceRefresh bool) (string, error)
+	// RefreshAccessToken replaces stale after Daraja rejected it. Callers
+	// holding the same stale token share a single refresh.
+	RefreshAccessToken(stale string) (string, error)
+}
+
+// Client talks to the Daraja API. All outbound calls share one http.Client.
+type Client struct {
+	config     *config.Config
+	tokens     TokenSource
+	httpClient *http.Client
+}
+
+// NewHTTPClient builds the transport used for every call to Daraja
+func NewHTTPClient(cfg *config.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 90 * time.Second
+
+	return &http.Client{
+		Timeout:   time.Duration(cfg.APITimeout) * time.Second,
+		Transport: transport,
+	}
+}
+
+func NewClient(cfg *config.Config, tokens TokenSource, httpClient *http.Client) *Client {
+	return &Client{
+		config:     cfg,
+		tokens:     tokens,
+		httpClient: httpClient,
+	}
+}
+
+func (c *Client) STKPush(ctx context.Context, req *STKPushRequest) (*STKPushResponse, error) {
+	var resp STKPushResponse
+	if err := c.post(ctx, "STK Push", c.config.STKPushURL(), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) STKQuery(ctx context.Context, req *STKQueryRequest) (*STKQueryResponse, error) {
+	var resp STKQueryResponse
+	if err := c.post(ctx, "STK Query", c.config.STKQueryURL(), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) B2CPayment(ctx context.Context, req *B2CPaymentRequest) (*Acknowledgement, error) {
+	var resp Acknowledgement
+	if err := c.post(ctx, "B2C", c.config.B2CURL(), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) B2BPayment(ctx context.Context, req *B2BPaymentRequest) (*Acknowledgement, error) {
+	var resp Acknowledgement
+	if err := c.post(ctx, "B2B", c.config.B2BURL(), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) RegisterC2BURLs(ctx context.Context, req *C2BRegisterURLRequest) (*C2BRegisterURLResponse, error) {
+	var resp C2BRegisterURLResponse
+	if err := c.post(ctx, "C2B Register", c.config.C2BRegisterURL(), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) TransactionStatus(ctx context.Context, req *TransactionStatusRequest) (*Acknowledgement, error) {
+	var resp Acknowledgement
+	if err := c.post(ctx, "Transaction Status", c.config.TransactionStatusURL(), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) AccountBalance(ctx context.Context, req *AccountBalanceRequest) (*Acknowledgement, error) {
+	var resp Acknowledgement
+	if err := c.post(ctx, "Account Balance", c.config.AccountBalanceURL(), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) Reversal(ctx context.Context, req *ReversalRequest) (*Acknowledgement, error) {
+	var resp Acknowledgement
+	if err := c.post(ctx, "Reversal", c.config.ReversalURL(), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// post sends an authenticated JSON request and decodes the response into
+// result. Non-200 responses are returned as *APIError. A request rejected for
+// its access token is sent once more with a fresh token.
+func (c *Client) post(ctx context.Context, name, url string, payload, result interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", name, err)
+	}
+
+	accessToken, err := c.tokens.GetAccessToken(false)
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	body, err := c.send(ctx, name, url, jsonData, accessToken)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.invalidToken() {
+		log.Printf("M-Pesa %s rejected the access token, refreshing and retrying", name)
+		if accessToken, err = c.tokens.RefreshAccessToken(accessToken); err != nil {
+			return fmt.Errorf("failed to refresh access token: %w", err)
+		}
+		body, err = c.send(ctx, name, url, jsonData, accessToken)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", name, err)
+	}
+	return nil
+}
+
+// send makes a single attempt and returns the body of a 200 response
+func (c *Client) send(ctx context.Context, name, url string, jsonData []byte, accessToken string) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", name, err)
+	}
+
+	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	startTime := time.Now()
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s request: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", name, err)
+	}
+
+	// Log response for debugging
+	log.Printf("M-Pesa %s Response: Status=%d, Duration=%v, Body=%s",
+		name, resp.StatusCode, time.Since(startTime), string(body))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,62 @@
+// ==========================
+// internal/daraja/errors.go
+// ==========================
+package daraja
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Daraja reports an expired or revoked token as 404.001.03 rather than a 401
+const errorCodeInvalidAccessToken = "404.001.03"
+
+// APIError is a non-200 response from Daraja
+type APIError struct {
+	StatusCode   int    `json:"-"`
+	RequestID    string `json:"requestId"`
+	ErrorCode    string `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+	Body         string `json:"-"` // Raw body, for responses that are not Daraja's error JSON
+}
+
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.ErrorCode == "" {
+		apiErr.Body = string(body)
+	}
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	if e.ErrorCode != "" {
+		return fmt.Sprintf("daraja error %s: %s (status %d, request %s)",
+			e.ErrorCode, e.ErrorMessage, e.StatusCode, e.RequestID)
+	}
+	return fmt.Sprintf("daraja error: status %d, body: %s", e.StatusCode, e.Body)
+}
+
+// Details returns the error fields for models.ErrorResponse.Details
+func (e *APIError) Details() map[string]interface{} {
+	details := map[string]interface{}{
+		"status_code": e.StatusCode,
+	}
+	if e.ErrorCode != "" {
+		details["requestId"] = e.RequestID
+		details["errorCode"] = e.ErrorCode
+		details["errorMessage"] = e.ErrorMessage
+	} else {
+		details["body"] = e.Body
+	}
+	return details
+}
+
+// invalidToken reports whether the request was refused because of its
+// access token
+func (e *APIError) invalidToken() bool {
+	return e.StatusCode == http.StatusUnauthorized ||
+		e.ErrorCode == errorCodeInvalidAccessToken ||
+		strings.Contains(strings.ToLower(e.ErrorMessage), "invalid access token")
+}
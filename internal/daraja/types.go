@@ -0,0 +1,145 @@
+// ==========================
+// internal/daraja/types.go
+// ==========================
+package daraja
+
+import "encoding/json"
+
+// Request and response bodies of the Daraja endpoints. Field names, including
+// their misspellings, match the M-Pesa API.
+
+type STKPushRequest struct {
+	BusinessShortCode string `json:"BusinessShortCode"`
+	Password          string `json:"Password"`
+	Timestamp         string `json:"Timestamp"`
+	TransactionType   string `json:"TransactionType"`
+	Amount            int    `json:"Amount"`
+	PartyA            string `json:"PartyA"`
+	PartyB            string `json:"PartyB"`
+	PhoneNumber       string `json:"PhoneNumber"`
+	CallBackURL       string `json:"CallBackURL"`
+	AccountReference  string `json:"AccountReference"`
+	TransactionDesc   string `json:"TransactionDesc"`
+}
+
+type STKPushResponse struct {
+	MerchantRequestID   string `json:"MerchantRequestID"`
+	CheckoutRequestID   string `json:"CheckoutRequestID"`
+	ResponseCode        string `json:"ResponseCode"`
+	ResponseDescription string `json:"ResponseDescription"`
+	CustomerMessage     string `json:"CustomerMessage"`
+}
+
+type STKQueryRequest struct {
+	BusinessShortCode string `json:"BusinessShortCode"`
+	Password          string `json:"Password"`
+	Timestamp         string `json:"Timestamp"`
+	CheckoutRequestID string `json:"CheckoutRequestID"`
+}
+
+type STKQueryResponse struct {
+	MerchantRequestID   string      `json:"MerchantRequestID"`
+	CheckoutRequestID   string      `json:"CheckoutRequestID"`
+	ResponseCode        string      `json:"ResponseCode"`
+	ResponseDescription string      `json:"ResponseDescription"`
+	ResultCode          json.Number `json:"ResultCode"` // Sent as a string
+	ResultDesc          string      `json:"ResultDesc"`
+}
+
+type B2CPaymentRequest struct {
+	OriginatorConversationID string `json:"OriginatorConversationID"`
+	InitiatorName            string `json:"InitiatorName"`
+	SecurityCredential       string `json:"SecurityCredential"`
+	CommandID                string `json:"CommandID"`
+	Amount                   int    `json:"Amount"`
+	PartyA                   string `json:"PartyA"`
+	PartyB                   string `json:"PartyB"`
+	Remarks                  string `json:"Remarks"`
+	QueueTimeOutURL          string `json:"QueueTimeOutURL"`
+	ResultURL                string `json:"ResultURL"`
+	Occasion                 string `json:"Occassion"`
+}
+
+type B2BPaymentRequest struct {
+	OriginatorConversationID string `json:"OriginatorConversationID,omitempty"`
+	Initiator                string `json:"Initiator"`
+	SecurityCredential       string `json:"SecurityCredential"`
+	CommandID                string `json:"CommandID"`
+	SenderIdentifierType     string `json:"SenderIdentifierType"`
+	ReceiverIdentifierType   string `json:"RecieverIdentifierType"`
+	Amount                   int    `json:"Amount"`
+	PartyA                   string `json:"PartyA"`
+	PartyB                   string `json:"PartyB"`
+	AccountReference         string `json:"AccountReference"`
+	Requester                string `json:"Requester,omitempty"`
+	Remarks                  string `json:"Remarks"`
+	QueueTimeOutURL          string `json:"QueueTimeOutURL"`
+	ResultURL                string `json:"ResultURL"`
+}
+
+type C2BRegisterURLRequest struct {
+	ShortCode       string `json:"ShortCode"`
+	ResponseType    string `json:"ResponseType"`
+	ConfirmationURL string `json:"ConfirmationURL"`
+	ValidationURL   string `json:"ValidationURL"`
+}
+
+type C2BRegisterURLResponse struct {
+	OriginatorConversationID string `json:"OriginatorCoversationID"`
+	ResponseCode             string `json:"ResponseCode"`
+	ResponseDescription      string `json:"ResponseDescription"`
+}
+
+type TransactionStatusRequest struct {
+	Initiator              string `json:"Initiator"`
+	SecurityCredential     string `json:"SecurityCredential"`
+	CommandID              string `json:"CommandID"`
+	TransactionID          string `json:"TransactionID"`
+	OriginalConversationID string `json:"OriginalConversationID,omitempty"`
+	PartyA                 string `json:"PartyA"`
+	IdentifierType         string `json:"IdentifierType"`
+	ResultURL              string `json:"ResultURL"`
+	QueueTimeOutURL        string `json:"QueueTimeOutURL"`
+	Remarks                string `json:"Remarks"`
+	Occasion               string `json:"Occasion"`
+}
+
+type AccountBalanceRequest struct {
+	Initiator          string `json:"Initiator"`
+	SecurityCredential string `json:"SecurityCredential"`
+	CommandID          string `json:"CommandID"`
+	PartyA             string `json:"PartyA"`
+	IdentifierType     string `json:"IdentifierType"`
+	Remarks            string `json:"Remarks"`
+	QueueTimeOutURL    string `json:"QueueTimeOutURL"`
+	ResultURL          string `json:"ResultURL"`
+}
+
+type ReversalRequest struct {
+	Initiator              string `json:"Initiator"`
+	SecurityCredential     string `json:"SecurityCredential"`
+	CommandID              string `json:"CommandID"`
+	TransactionID          string `json:"TransactionID"`
+	Amount                 int    `json:"Amount"`
+	ReceiverParty          string `json:"ReceiverParty"`
+	ReceiverIdentifierType string `json:"RecieverIdentifierType"`
+	ResultURL              string `json:"ResultURL"`
+	QueueTimeOutURL        string `json:"QueueTimeOutURL"`
+	Remarks                string `json:"Remarks"`
+	Occasion               string `json:"Occasion"`
+}
+
+// Acknowledgement is the synchronous response of Daraja's asynchronous
+// APIs; the outcome is posted to the ResultURL later
+type Acknowledgement struct {
+	ConversationID           string `json:"ConversationID"`
+	OriginatorConversationID string `json:"OriginatorConversationID"`
+	ResponseCode             string `json:"ResponseCode"`
+	ResponseDescription      string `json:"ResponseDescription"`
+}
+
+// Identifier types used by PartyA/PartyB fields
+const (
+	IdentifierTypeShortCode    = "4"
+	IdentifierTypeOrganization = "11"
+)
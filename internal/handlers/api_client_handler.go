@@ -0,0 +1,156 @@
+// ==========================
+// internal/handlers/api_client_handler.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/middleware"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIClientHandler struct {
+	config        *config.Config
+	clientService *services.APIClientService
+}
+
+func NewAPIClientHandler(cfg *config.Config, clientService *services.APIClientService) *APIClientHandler {
+	return &APIClientHandler{
+		config:        cfg,
+		clientService: clientService,
+	}
+}
+
+// CreateClient registers an API client. Its key is only ever returned here.
+func (h *APIClientHandler) CreateClient(c *gin.Context) {
+	var req models.APIClientRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			ErrorCode: "INVALID_REQUEST",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	creator, ok := middleware.Client(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error:     "API key required",
+			ErrorCode: "UNAUTHORIZED",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	client, key, err := h.clientService.Create(c.Request.Context(), req, creator)
+	if errors.Is(err, services.ErrScopeNotHeld) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:     "Cannot grant a scope the creating client does not hold",
+			ErrorCode: "SCOPE_NOT_HELD",
+			Details:   map[string]interface{}{"error": err.Error(), "scopes": creator.Scopes},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if errors.Is(err, services.ErrUnknownScope) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Unknown scope",
+			ErrorCode: "INVALID_SCOPE",
+			Details:   map[string]interface{}{"error": err.Error(), "scopes": models.Scopes},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create API client: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to create API client",
+			ErrorCode: "CLIENT_CREATE_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse{
+		Message: "API client created",
+		Data: map[string]interface{}{
+			"client":  client,
+			"api_key": key,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *APIClientHandler) ListClients(c *gin.Context) {
+	clients, err := h.clientService.List(c.Request.Context())
+	if err != nil {
+		h.storeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message:   "API clients retrieved",
+		Data:      map[string]interface{}{"clients": clients},
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *APIClientHandler) GetClient(c *gin.Context) {
+	client, err := h.clientService.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.storeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message:   "API client retrieved",
+		Data:      map[string]interface{}{"client": client},
+		Timestamp: time.Now(),
+	})
+}
+
+// RevokeClient disables a client's key; its transactions keep its ID
+func (h *APIClientHandler) RevokeClient(c *gin.Context) {
+	if err := h.clientService.Revoke(c.Request.Context(), c.Param("id")); err != nil {
+		h.storeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message:   "API client revoked",
+		Data:      map[string]interface{}{"id": c.Param("id")},
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *APIClientHandler) storeError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:     "API client not found",
+			ErrorCode: "NOT_FOUND",
+			Details:   map[string]interface{}{"id": c.Param("id")},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	log.Printf("API client store error: %v", err)
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:     "Failed to access API clients",
+		ErrorCode: "CLIENT_STORE_FAILED",
+		Details:   map[string]interface{}{"error": err.Error()},
+		Timestamp: time.Now(),
+	})
+}
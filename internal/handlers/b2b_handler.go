@@ -0,0 +1,261 @@
+// ==========================
+// internal/handlers/b2b_handler.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/middleware"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
+	"awesomeProject/internal/utils"
+	ws "awesomeProject/internal/websocket"
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type B2BHandler struct {
+	config     *config.Config
+	b2bService *services.B2BService
+	repo       storage.TransactionRepository
+	hub        *ws.Hub
+}
+
+func NewB2BHandler(cfg *config.Config, b2bService *services.B2BService, repo storage.TransactionRepository, hub *ws.Hub) *B2BHandler {
+	return &B2BHandler{
+		config:     cfg,
+		b2bService: b2bService,
+		repo:       repo,
+		hub:        hub,
+	}
+}
+
+func (h *B2BHandler) InitiatePayment(c *gin.Context) {
+	var req models.B2BPaymentRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			ErrorCode: "INVALID_REQUEST",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Paybills need to know which account is being paid
+	if req.CommandID == "BusinessPayBill" && req.AccountReference == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "account_reference is required for BusinessPayBill",
+			ErrorCode: "INVALID_REQUEST",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Format requester phone number if provided
+	if req.Requester != "" {
+		formattedPhone, err := utils.FormatPhoneNumber(req.Requester)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "Invalid requester phone number",
+				ErrorCode: "INVALID_PHONE",
+				Details:   map[string]interface{}{"error": err.Error()},
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		req.Requester = formattedPhone
+	}
+
+	// Generate OriginatorConversationID if not provided
+	if req.OriginatorConversationID == "" {
+		req.OriginatorConversationID = uuid.New().String()
+	}
+
+	if req.Remarks == "" {
+		req.Remarks = "Payment"
+	}
+
+	tx := &models.Transaction{
+		ID:                       uuid.New().String(),
+		Type:                     models.TransactionTypeB2B,
+		PhoneNumber:              req.Requester,
+		Amount:                   req.Amount,
+		AccountReference:         req.AccountReference,
+		CommandID:                req.CommandID,
+		OriginatorConversationID: req.OriginatorConversationID,
+		ClientID:                 middleware.ClientID(c),
+		Metadata: map[string]interface{}{
+			"receiver_short_code": req.ReceiverShortCode,
+			"remarks":             req.Remarks,
+		},
+	}
+	if !storePending(c, h.repo, tx) {
+		return
+	}
+
+	// Initiate payment
+	resp, err := h.b2bService.InitiatePayment(c.Request.Context(), &req, tx.ID)
+	if err != nil {
+		log.Printf("B2B payment error: %v", err)
+		markFailed(c.Request.Context(), h.repo, tx, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to initiate payment",
+			ErrorCode: "PAYMENT_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Results are matched on the IDs Daraja acknowledged the payment with
+	tx.ConversationID = resp.ConversationID
+	tx.OriginatorConversationID = resp.OriginatorConversationID
+	recordInitiated(c.Request.Context(), h.repo, tx)
+
+	// Broadcast initiation via WebSocket
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventB2BInitiated,
+		models.EventCorrelation{
+			TransactionRef:           tx.ID,
+			ConversationID:           resp.ConversationID,
+			OriginatorConversationID: resp.OriginatorConversationID,
+		},
+		models.PaymentInitiatedPayload{
+			Amount:            req.Amount,
+			CommandID:         req.CommandID,
+			ReceiverShortCode: req.ReceiverShortCode,
+			AccountReference:  req.AccountReference,
+		}))
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Payment initiated successfully",
+		Data: map[string]interface{}{
+			"conversation_id":            resp.ConversationID,
+			"originator_conversation_id": resp.OriginatorConversationID,
+			"response_code":              resp.ResponseCode,
+			"response_description":       resp.ResponseDescription,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *B2BHandler) HandleCallback(c *gin.Context) {
+	var callbackReq models.B2BResultRequest
+
+	if err := c.ShouldBindJSON(&callbackReq); err != nil {
+		log.Printf("B2B callback binding error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid callback data"})
+		return
+	}
+
+	result := callbackReq.Result
+	if !verifyCallbackRef(c, h.repo.GetByOriginatorConversationID, result.OriginatorConversationID) {
+		return
+	}
+
+	log.Printf("B2B Callback received - ConversationID: %s, ResultCode: %d, ResultDesc: %s",
+		result.ConversationID, result.ResultCode, result.ResultDesc)
+
+	status := models.TransactionStatusSuccess
+	if result.ResultCode != 0 {
+		status = models.TransactionStatusFailed
+	}
+	tx, ok := h.recordResult(c.Request.Context(), &result, status)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+		return
+	}
+
+	// Broadcast callback via WebSocket
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventB2BCallback,
+		paymentCorrelation(tx, result.ConversationID, result.OriginatorConversationID, result.TransactionID),
+		models.PaymentResultPayload{
+			Status:           status,
+			ResultCode:       result.ResultCode,
+			ResultDesc:       result.ResultDesc,
+			ResultParameters: result.GetResultParametersMap(),
+		}))
+
+	c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+}
+
+func (h *B2BHandler) HandleTimeout(c *gin.Context) {
+	var callbackReq models.B2BResultRequest
+
+	if err := c.ShouldBindJSON(&callbackReq); err != nil {
+		log.Printf("B2B timeout binding error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timeout data"})
+		return
+	}
+
+	result := callbackReq.Result
+	if !verifyCallbackRef(c, h.repo.GetByOriginatorConversationID, result.OriginatorConversationID) {
+		return
+	}
+
+	log.Printf("B2B Timeout - ConversationID: %s, ResultDesc: %s",
+		result.ConversationID, result.ResultDesc)
+
+	tx, ok := h.recordResult(c.Request.Context(), &result, models.TransactionStatusTimeout)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+		return
+	}
+
+	// Broadcast timeout via WebSocket
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventB2BTimeout,
+		paymentCorrelation(tx, result.ConversationID, result.OriginatorConversationID, ""),
+		models.PaymentResultPayload{
+			Status:     models.TransactionStatusTimeout,
+			ResultCode: result.ResultCode,
+			ResultDesc: result.ResultDesc,
+		}))
+
+	c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+}
+
+// recordResult settles the pending payment with its outcome and returns it,
+// or nil if the payment is unknown. ok is false if the payment was already
+// settled, by an earlier result or a timeout, and nothing should be sent.
+func (h *B2BHandler) recordResult(ctx context.Context, result *models.B2BCallback, status models.TransactionStatus) (tx *models.Transaction, ok bool) {
+	tx, err := h.repo.GetByOriginatorConversationID(ctx, result.OriginatorConversationID)
+	if err != nil {
+		log.Printf("No stored B2B transaction for %s: %v", result.OriginatorConversationID, err)
+		return nil, true
+	}
+
+	settled, err := h.repo.UpdateStatusIf(ctx, tx.ID, models.TransactionStatusPending, status)
+	if err != nil {
+		log.Printf("Failed to settle B2B transaction %s: %v", result.OriginatorConversationID, err)
+		return nil, false
+	}
+	if !settled {
+		log.Printf("B2B transaction %s already settled, ignoring outcome %s", result.OriginatorConversationID, status)
+		return nil, false
+	}
+	tx.SetResult(status, result.ResultCode, result.ResultDesc)
+	if tx.ConversationID == "" {
+		tx.ConversationID = result.ConversationID
+	}
+	if result.TransactionID != "" {
+		tx.ReceiptNumber = result.TransactionID
+	}
+	if params := result.GetResultParametersMap(); len(params) > 0 {
+		if tx.Metadata == nil {
+			tx.Metadata = make(map[string]interface{})
+		}
+		tx.Metadata["result_parameters"] = params
+	}
+
+	if err := h.repo.Update(ctx, tx); err != nil {
+		log.Printf("Failed to update B2B transaction %s: %v", result.OriginatorConversationID, err)
+	}
+	return tx, true
+}
@@ -0,0 +1,435 @@
+// ==========================
+// internal/handlers/b2c_approval_handler.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/middleware"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	maxPendingApprovals = 200
+	systemActor         = "system" // Actor of decisions nobody took, such as expiry
+)
+
+// holdPayment stores a payout that needs approval instead of sending it
+func (h *B2CHandler) holdPayment(c *gin.Context, req *models.B2CPaymentRequest, reasons []models.ApprovalReason) {
+	approval := map[string]interface{}{"reasons": reasons}
+	tx := &models.Transaction{
+		ID:                       uuid.New().String(),
+		Type:                     models.TransactionTypeB2C,
+		Status:                   models.TransactionStatusAwaitingApproval,
+		PhoneNumber:              req.PhoneNumber,
+		Amount:                   req.Amount,
+		CommandID:                req.CommandID,
+		OriginatorConversationID: req.OriginatorConversationID,
+		ClientID:                 middleware.ClientID(c),
+		Metadata: map[string]interface{}{
+			"remarks":  req.Remarks,
+			"occasion": req.Occasion,
+			"approval": approval,
+		},
+	}
+
+	// Unlike a sent payout, a held one only exists in the store
+	err := h.repo.Create(c.Request.Context(), tx)
+	if err != nil {
+		middleware.ReleaseIdempotencyKey(c)
+	}
+	if errors.Is(err, storage.ErrDuplicate) {
+		duplicateTransaction(c, tx)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to store held B2C payment %s: %v", req.OriginatorConversationID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to hold payment for approval",
+			ErrorCode: "PAYMENT_HOLD_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	data := map[string]interface{}{
+		"id":                         tx.ID,
+		"originator_conversation_id": tx.OriginatorConversationID,
+		"status":                     tx.Status,
+		"reasons":                    reasons,
+	}
+	if h.config.B2CApprovalTTL > 0 {
+		data["expires_at"] = h.approvals.ExpiresAt(tx.CreatedAt)
+	}
+
+	log.Printf("B2C payment %s of %d to %s held for approval: %v", tx.ID, tx.Amount, tx.PhoneNumber, reasons)
+	h.recordAudit(c.Request.Context(), models.AuditB2CApprovalRequested, tx.ClientID, tx.ID, map[string]interface{}{
+		"amount":       tx.Amount,
+		"command_id":   tx.CommandID,
+		"phone_number": tx.PhoneNumber,
+		"reasons":      reasons,
+	})
+	h.broadcastApproval(models.EventB2CApprovalRequired, tx, models.ApprovalPayload{
+		Amount:      tx.Amount,
+		CommandID:   tx.CommandID,
+		Reasons:     reasons,
+		RequestedBy: tx.ClientID,
+	})
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse{
+		Message:   "Payment held for approval",
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+}
+
+// ListApprovals lists the payouts waiting for a decision, newest first
+func (h *B2CHandler) ListApprovals(c *gin.Context) {
+	pending, err := h.repo.List(c.Request.Context(), storage.TransactionFilter{
+		Type:     models.TransactionTypeB2C,
+		Statuses: []models.TransactionStatus{models.TransactionStatusAwaitingApproval},
+		Limit:    maxPendingApprovals,
+	})
+	if err != nil {
+		log.Printf("Failed to list held B2C payments: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to list approvals",
+			ErrorCode: "APPROVAL_LOOKUP_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message:   "Pending approvals retrieved",
+		Data:      map[string]interface{}{"payments": pending},
+		Timestamp: time.Now(),
+	})
+}
+
+// GetApproval shows a held payout with its audit trail
+func (h *B2CHandler) GetApproval(c *gin.Context) {
+	tx, ok := h.heldPayment(c)
+	if !ok {
+		return
+	}
+
+	trail, err := h.audit.List(c.Request.Context(), tx.ID)
+	if err != nil {
+		log.Printf("Failed to read audit trail of %s: %v", tx.ID, err)
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Approval retrieved",
+		Data: map[string]interface{}{
+			"payment": tx,
+			"audit":   trail,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// DecideApproval approves or rejects a held payout. The deciding API client
+// must be independent of the one that initiated it: neither created the
+// other and they share no creator. Approval sends the payout.
+func (h *B2CHandler) DecideApproval(c *gin.Context) {
+	var req models.B2CApprovalDecisionRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			ErrorCode: "INVALID_REQUEST",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	tx, ok := h.heldPayment(c)
+	if !ok {
+		return
+	}
+
+	checker := middleware.ClientID(c)
+	independent, err := h.approvals.Independent(c.Request.Context(), tx.ClientID, checker)
+	if err != nil {
+		log.Printf("Failed to check independence of %s from %s: %v", checker, tx.ClientID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to check approval policy",
+			ErrorCode: "APPROVAL_CHECK_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if checker == tx.ClientID || !independent {
+		log.Printf("API client %s tried to decide on B2C payment %s of API client %s", checker, tx.ID, tx.ClientID)
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:     "Payouts must be approved by an API client independent of the one that requested them",
+			ErrorCode: "SELF_APPROVAL",
+			Details:   map[string]interface{}{"requested_by": tx.ClientID},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if tx.Status != models.TransactionStatusAwaitingApproval {
+		h.notAwaitingApproval(c, tx.Status)
+		return
+	}
+	if h.approvals.Expired(tx) {
+		h.expirePayment(c, tx)
+		return
+	}
+
+	if req.Decision == models.ApprovalDecisionReject {
+		h.rejectPayment(c, tx, checker, req.Note)
+		return
+	}
+	h.approvePayment(c, tx, checker, req.Note)
+}
+
+func (h *B2CHandler) approvePayment(c *gin.Context, tx *models.Transaction, checker, note string) {
+	ctx := c.Request.Context()
+	if !h.checkBalance(c, tx.Amount) {
+		return
+	}
+
+	// Limits and balances may have moved while the payout waited
+	release, ok := checkPayoutRules(c, h.rules, &services.Payout{
+		Type:        models.TransactionTypeB2C,
+		PhoneNumber: tx.PhoneNumber,
+		Amount:      tx.Amount,
+		CommandID:   tx.CommandID,
+		ClientID:    tx.ClientID,
+		Held:        true,
+	})
+	if !ok {
+		return
+	}
+
+	// Claim the payout so a concurrent decision cannot send it twice
+	claimed := h.claimHeldPayment(c, tx.ID, models.TransactionStatusPending)
+	release()
+	if !claimed {
+		return
+	}
+
+	remarks, _ := tx.Metadata["remarks"].(string)
+	occasion, _ := tx.Metadata["occasion"].(string)
+	req := &models.B2CPaymentRequest{
+		PhoneNumber:              tx.PhoneNumber,
+		Amount:                   tx.Amount,
+		CommandID:                tx.CommandID,
+		Remarks:                  remarks,
+		Occasion:                 occasion,
+		OriginatorConversationID: tx.OriginatorConversationID,
+	}
+
+	resp, err := h.b2cService.InitiatePayment(ctx, req, tx.ID)
+	if err != nil {
+		// Nothing was sent; the payout waits for another attempt
+		if _, revertErr := h.repo.UpdateStatusIf(ctx, tx.ID, models.TransactionStatusPending, models.TransactionStatusAwaitingApproval); revertErr != nil {
+			log.Printf("Failed to return B2C payment %s to approval: %v", tx.ID, revertErr)
+		}
+		log.Printf("B2C payment error for approved %s: %v", tx.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to initiate payment",
+			ErrorCode: "PAYMENT_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	tx.Status = models.TransactionStatusPending
+	tx.ConversationID = resp.ConversationID
+	recordDecision(tx, checker, models.ApprovalDecisionApprove, note)
+	if err := h.repo.Update(ctx, tx); err != nil {
+		log.Printf("Failed to update approved B2C payment %s: %v", tx.ID, err)
+	}
+
+	h.recordAudit(ctx, models.AuditB2CApproved, checker, tx.ID, map[string]interface{}{
+		"requested_by":    tx.ClientID,
+		"note":            note,
+		"conversation_id": resp.ConversationID,
+	})
+	h.broadcastApproval(models.EventB2CApproved, tx, models.ApprovalPayload{
+		Amount:      tx.Amount,
+		CommandID:   tx.CommandID,
+		RequestedBy: tx.ClientID,
+		DecidedBy:   checker,
+		Note:        note,
+	})
+
+	h.paymentInitiated(c, tx.ID, req, resp, "Payment approved and initiated")
+}
+
+func (h *B2CHandler) rejectPayment(c *gin.Context, tx *models.Transaction, checker, note string) {
+	if !h.claimHeldPayment(c, tx.ID, models.TransactionStatusRejected) {
+		return
+	}
+
+	tx.Status = models.TransactionStatusRejected
+	tx.ResultDesc = "Rejected by approver"
+	recordDecision(tx, checker, models.ApprovalDecisionReject, note)
+	h.closeHeldPayment(c.Request.Context(), tx, models.AuditB2CRejected, checker, note)
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message:   "Payment rejected",
+		Data:      map[string]interface{}{"payment": tx},
+		Timestamp: time.Now(),
+	})
+}
+
+// expirePayment cancels a payout nobody decided on within B2CApprovalTTL
+func (h *B2CHandler) expirePayment(c *gin.Context, tx *models.Transaction) {
+	if !h.claimHeldPayment(c, tx.ID, models.TransactionStatusCancelled) {
+		return
+	}
+
+	tx.Status = models.TransactionStatusCancelled
+	tx.ResultDesc = "Approval expired"
+	h.closeHeldPayment(c.Request.Context(), tx, models.AuditB2CApprovalExpired, systemActor, tx.ResultDesc)
+
+	c.JSON(http.StatusConflict, models.ErrorResponse{
+		Error:     "Approval expired",
+		ErrorCode: "APPROVAL_EXPIRED",
+		Details:   map[string]interface{}{"id": tx.ID, "held_at": tx.CreatedAt},
+		Timestamp: time.Now(),
+	})
+}
+
+// closeHeldPayment saves, audits and broadcasts a payout that will not be sent
+func (h *B2CHandler) closeHeldPayment(ctx context.Context, tx *models.Transaction, action models.AuditAction, actor, note string) {
+	if err := h.repo.Update(ctx, tx); err != nil {
+		log.Printf("Failed to update held B2C payment %s: %v", tx.ID, err)
+	}
+
+	h.recordAudit(ctx, action, actor, tx.ID, map[string]interface{}{
+		"requested_by": tx.ClientID,
+		"note":         note,
+	})
+	h.broadcastApproval(models.EventB2CRejected, tx, models.ApprovalPayload{
+		Amount:      tx.Amount,
+		CommandID:   tx.CommandID,
+		RequestedBy: tx.ClientID,
+		DecidedBy:   actor,
+		Note:        note,
+	})
+}
+
+// heldPayment loads the B2C payment named by :id, answering 404 if there is none
+func (h *B2CHandler) heldPayment(c *gin.Context) (*models.Transaction, bool) {
+	tx, err := h.repo.GetByID(c.Request.Context(), c.Param("id"))
+	if err == nil && tx.Type == models.TransactionTypeB2C {
+		return tx, true
+	}
+
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Failed to look up B2C payment %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to look up payment",
+			ErrorCode: "APPROVAL_LOOKUP_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return nil, false
+	}
+
+	c.JSON(http.StatusNotFound, models.ErrorResponse{
+		Error:     "Payment not found",
+		ErrorCode: "NOT_FOUND",
+		Details:   map[string]interface{}{"id": c.Param("id")},
+		Timestamp: time.Now(),
+	})
+	return nil, false
+}
+
+// claimHeldPayment moves a payout out of AWAITING_APPROVAL, answering 409 if
+// another decision got there first
+func (h *B2CHandler) claimHeldPayment(c *gin.Context, id string, to models.TransactionStatus) bool {
+	claimed, err := h.repo.UpdateStatusIf(c.Request.Context(), id, models.TransactionStatusAwaitingApproval, to)
+	if err != nil {
+		log.Printf("Failed to claim held B2C payment %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to record decision",
+			ErrorCode: "APPROVAL_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return false
+	}
+	if !claimed {
+		h.notAwaitingApproval(c, "")
+		return false
+	}
+	return true
+}
+
+func (h *B2CHandler) notAwaitingApproval(c *gin.Context, status models.TransactionStatus) {
+	details := map[string]interface{}{"id": c.Param("id")}
+	if status != "" {
+		details["status"] = status
+	}
+	c.JSON(http.StatusConflict, models.ErrorResponse{
+		Error:     "Payment is not awaiting approval",
+		ErrorCode: "APPROVAL_NOT_PENDING",
+		Details:   details,
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *B2CHandler) broadcastApproval(eventType models.EventType, tx *models.Transaction, payload models.ApprovalPayload) {
+	h.hub.BroadcastPaymentStatus(models.NewEvent(eventType,
+		models.EventCorrelation{
+			TransactionRef:           tx.ID,
+			ConversationID:           tx.ConversationID,
+			OriginatorConversationID: tx.OriginatorConversationID,
+			PhoneNumber:              tx.PhoneNumber,
+		},
+		payload))
+}
+
+func (h *B2CHandler) recordAudit(ctx context.Context, action models.AuditAction, actor, subject string, details map[string]interface{}) {
+	if err := h.audit.Record(ctx, &models.AuditEntry{
+		ID:        uuid.New().String(),
+		Action:    action,
+		ActorID:   actor,
+		SubjectID: subject,
+		Details:   details,
+	}); err != nil {
+		log.Printf("⚠️ Failed to audit %s on %s by %s: %v", action, subject, actor, err)
+	}
+}
+
+// recordDecision notes the decision in the payout's approval metadata
+func recordDecision(tx *models.Transaction, checker string, decision models.ApprovalDecision, note string) {
+	if tx.Metadata == nil {
+		tx.Metadata = make(map[string]interface{})
+	}
+	approval := make(map[string]interface{})
+	if held, ok := tx.Metadata["approval"].(map[string]interface{}); ok {
+		for k, v := range held {
+			approval[k] = v
+		}
+	}
+	approval["decision"] = decision
+	approval["decided_by"] = checker
+	approval["decided_at"] = time.Now()
+	if note != "" {
+		approval["note"] = note
+	}
+	tx.Metadata["approval"] = approval
+}
@@ -5,10 +5,14 @@ package handlers
 
 import (
 	"awesomeProject/internal/config"
+	"awesomeProject/internal/middleware"
 	"awesomeProject/internal/models"
 	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
 	"awesomeProject/internal/utils"
 	ws "awesomeProject/internal/websocket"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -18,16 +22,26 @@ import (
 )
 
 type B2CHandler struct {
-	config     *config.Config
-	b2cService *services.B2CService
-	hub        *ws.Hub
+	config         *config.Config
+	b2cService     *services.B2CService
+	balanceService *services.AccountBalanceService
+	approvals      *services.PayoutApprovals
+	rules          *services.PayoutRules
+	repo           storage.TransactionRepository
+	audit          storage.AuditLog
+	hub            *ws.Hub
 }
 
-func NewB2CHandler(cfg *config.Config, b2cService *services.B2CService, hub *ws.Hub) *B2CHandler {
+func NewB2CHandler(cfg *config.Config, b2cService *services.B2CService, balanceService *services.AccountBalanceService, approvals *services.PayoutApprovals, rules *services.PayoutRules, repo storage.TransactionRepository, audit storage.AuditLog, hub *ws.Hub) *B2CHandler {
 	return &B2CHandler{
-		config:     cfg,
-		b2cService: b2cService,
-		hub:        hub,
+		config:         cfg,
+		b2cService:     b2cService,
+		balanceService: balanceService,
+		approvals:      approvals,
+		rules:          rules,
+		repo:           repo,
+		audit:          audit,
+		hub:            hub,
 	}
 }
 
@@ -70,10 +84,66 @@ func (h *B2CHandler) InitiatePayment(c *gin.Context) {
 		req.Remarks = "Payment"
 	}
 
+	if !h.checkBalance(c, req.Amount) {
+		return
+	}
+
+	// Refuse payouts over the configured limits outright
+	release, ok := checkPayoutRules(c, h.rules, &services.Payout{
+		Type:        models.TransactionTypeB2C,
+		PhoneNumber: req.PhoneNumber,
+		Amount:      req.Amount,
+		CommandID:   req.CommandID,
+		ClientID:    middleware.ClientID(c),
+	})
+	if !ok {
+		return
+	}
+
+	// Large payouts and new recipients wait for a second client to approve them
+	reasons, err := h.approvals.Reasons(c.Request.Context(), &req)
+	if err != nil {
+		release()
+		middleware.ReleaseIdempotencyKey(c)
+		log.Printf("B2C approval check error: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to check approval policy",
+			ErrorCode: "APPROVAL_CHECK_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if len(reasons) > 0 {
+		h.holdPayment(c, &req, reasons)
+		release()
+		return
+	}
+
+	tx := &models.Transaction{
+		ID:                       uuid.New().String(),
+		Type:                     models.TransactionTypeB2C,
+		PhoneNumber:              req.PhoneNumber,
+		Amount:                   req.Amount,
+		CommandID:                req.CommandID,
+		OriginatorConversationID: req.OriginatorConversationID,
+		ClientID:                 middleware.ClientID(c),
+		Metadata: map[string]interface{}{
+			"remarks":  req.Remarks,
+			"occasion": req.Occasion,
+		},
+	}
+	stored := storePending(c, h.repo, tx)
+	release()
+	if !stored {
+		return
+	}
+
 	// Initiate payment
-	resp, err := h.b2cService.InitiatePayment(&req)
+	resp, err := h.b2cService.InitiatePayment(c.Request.Context(), &req, tx.ID)
 	if err != nil {
 		log.Printf("B2C payment error: %v", err)
+		markFailed(c.Request.Context(), h.repo, tx, err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:     "Failed to initiate payment",
 			ErrorCode: "PAYMENT_FAILED",
@@ -83,20 +153,53 @@ func (h *B2CHandler) InitiatePayment(c *gin.Context) {
 		return
 	}
 
-	// Broadcast initiation via WebSocket
-	h.hub.BroadcastPaymentStatus(map[string]interface{}{
-		"type":                       "b2c_initiated",
-		"conversation_id":            resp.ConversationID,
-		"originator_conversation_id": resp.OriginatorConversationID,
-		"phone_number":               req.PhoneNumber,
-		"amount":                     req.Amount,
-		"command_id":                 req.CommandID,
-		"timestamp":                  time.Now(),
+	tx.ConversationID = resp.ConversationID
+	recordInitiated(c.Request.Context(), h.repo, tx)
+
+	h.paymentInitiated(c, tx.ID, &req, resp, "Payment initiated successfully")
+}
+
+// checkBalance rejects payouts we already know the payout account cannot
+// cover. The balance changes, so the request's idempotency key is freed.
+func (h *B2CHandler) checkBalance(c *gin.Context, amount int) bool {
+	available, ok := h.balanceService.PayoutAvailable(c.Request.Context())
+	if !ok || float64(amount) <= available {
+		return true
+	}
+
+	middleware.ReleaseIdempotencyKey(c)
+
+	c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+		Error:     "Insufficient balance for payout",
+		ErrorCode: "INSUFFICIENT_BALANCE",
+		Details: map[string]interface{}{
+			"amount":    amount,
+			"available": available,
+			"account":   h.config.PayoutAccount,
+		},
+		Timestamp: time.Now(),
 	})
+	return false
+}
+
+// paymentInitiated broadcasts and answers a payout Daraja accepted
+func (h *B2CHandler) paymentInitiated(c *gin.Context, id string, req *models.B2CPaymentRequest, resp *models.B2CPaymentResponse, message string) {
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventB2CInitiated,
+		models.EventCorrelation{
+			TransactionRef:           id,
+			ConversationID:           resp.ConversationID,
+			OriginatorConversationID: resp.OriginatorConversationID,
+			PhoneNumber:              req.PhoneNumber,
+		},
+		models.PaymentInitiatedPayload{
+			Amount:    req.Amount,
+			CommandID: req.CommandID,
+		}))
 
 	c.JSON(http.StatusOK, models.SuccessResponse{
-		Message: "Payment initiated successfully",
+		Message: message,
 		Data: map[string]interface{}{
+			"id":                         id,
 			"conversation_id":            resp.ConversationID,
 			"originator_conversation_id": resp.OriginatorConversationID,
 			"response_code":              resp.ResponseCode,
@@ -116,31 +219,46 @@ func (h *B2CHandler) HandleCallback(c *gin.Context) {
 	}
 
 	result := callbackReq.Result
+	if !verifyCallbackRef(c, h.repo.GetByOriginatorConversationID, result.OriginatorConversationID) {
+		return
+	}
 
 	log.Printf("B2C Callback received - ConversationID: %s, ResultCode: %d, ResultDesc: %s",
 		result.ConversationID, result.ResultCode, result.ResultDesc)
 
+	tx, err := h.checkResult(c.Request.Context(), &result, models.EventB2CCallback)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "Temporarily unavailable"})
+		return
+	}
+	if tx == nil {
+		c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+		return
+	}
+
 	// Parse result parameters if successful
+	status := models.TransactionStatusSuccess
+	var settled bool
 	if result.ResultCode == 0 {
-		h.processSuccessfulPayment(&result)
+		settled = h.processSuccessfulPayment(c.Request.Context(), tx, &result)
 	} else {
-		h.processFailedPayment(&result)
+		settled = h.processFailedPayment(c.Request.Context(), tx, &result)
+		status = models.TransactionStatusFailed
+	}
+	if !settled {
+		c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+		return
 	}
-
-	// Get parameters as map for broadcasting
-	resultParams := result.GetResultParametersMap()
 
 	// Broadcast callback via WebSocket
-	h.hub.BroadcastPaymentStatus(map[string]interface{}{
-		"type":                       "b2c_callback",
-		"conversation_id":            result.ConversationID,
-		"originator_conversation_id": result.OriginatorConversationID,
-		"transaction_id":             result.TransactionID,
-		"result_code":                result.ResultCode,
-		"result_desc":                result.ResultDesc,
-		"result_parameters":          resultParams,
-		"timestamp":                  time.Now(),
-	})
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventB2CCallback,
+		paymentCorrelation(tx, result.ConversationID, result.OriginatorConversationID, result.TransactionID),
+		models.PaymentResultPayload{
+			Status:           status,
+			ResultCode:       result.ResultCode,
+			ResultDesc:       result.ResultDesc,
+			ResultParameters: result.GetResultParametersMap(),
+		}))
 
 	c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
 }
@@ -155,23 +273,41 @@ func (h *B2CHandler) HandleTimeout(c *gin.Context) {
 	}
 
 	result := callbackReq.Result
+	if !verifyCallbackRef(c, h.repo.GetByOriginatorConversationID, result.OriginatorConversationID) {
+		return
+	}
 
 	log.Printf("B2C Timeout - ConversationID: %s, ResultDesc: %s",
 		result.ConversationID, result.ResultDesc)
 
+	tx, err := h.checkResult(c.Request.Context(), &result, models.EventB2CTimeout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "Temporarily unavailable"})
+		return
+	}
+	if tx == nil {
+		c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+		return
+	}
+
+	if !h.recordResult(c.Request.Context(), tx, &result, models.TransactionStatusTimeout) {
+		c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+		return
+	}
+
 	// Broadcast timeout via WebSocket
-	h.hub.BroadcastPaymentStatus(map[string]interface{}{
-		"type":                       "b2c_timeout",
-		"conversation_id":            result.ConversationID,
-		"originator_conversation_id": result.OriginatorConversationID,
-		"result_desc":                result.ResultDesc,
-		"timestamp":                  time.Now(),
-	})
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventB2CTimeout,
+		paymentCorrelation(tx, result.ConversationID, result.OriginatorConversationID, ""),
+		models.PaymentResultPayload{
+			Status:     models.TransactionStatusTimeout,
+			ResultCode: result.ResultCode,
+			ResultDesc: result.ResultDesc,
+		}))
 
 	c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
 }
 
-func (h *B2CHandler) processSuccessfulPayment(result *models.B2CCallback) {
+func (h *B2CHandler) processSuccessfulPayment(ctx context.Context, tx *models.Transaction, result *models.B2CCallback) bool {
 	log.Printf("B2C Payment successful - TransactionID: %s", result.TransactionID)
 
 	// Extract payment details using helper method
@@ -193,19 +329,82 @@ func (h *B2CHandler) processSuccessfulPayment(result *models.B2CCallback) {
 	}
 
 	// Here you can:
-	// - Update database records
 	// - Send notifications
 	// - Trigger webhooks
 	// - Update transaction status
+	return h.recordResult(ctx, tx, result, models.TransactionStatusSuccess)
 }
 
-func (h *B2CHandler) processFailedPayment(result *models.B2CCallback) {
+func (h *B2CHandler) processFailedPayment(ctx context.Context, tx *models.Transaction, result *models.B2CCallback) bool {
 	log.Printf("B2C Payment failed - ResultCode: %d, ResultDesc: %s",
 		result.ResultCode, result.ResultDesc)
 
 	// Here you can:
-	// - Update database with failure status
 	// - Send failure notifications
 	// - Log for analysis
 	// - Trigger retry logic if applicable
+	return h.recordResult(ctx, tx, result, models.TransactionStatusFailed)
+}
+
+// checkResult finds the payment a result is about and cross-checks the
+// result against it. An unknown payment or a disagreeing result is raised as
+// a security alert and nil is returned; err is only set if the lookup failed.
+func (h *B2CHandler) checkResult(ctx context.Context, result *models.B2CCallback, source models.EventType) (*models.Transaction, error) {
+	correlation := paymentCorrelation(nil, result.ConversationID, result.OriginatorConversationID, result.TransactionID)
+	alert := models.SecurityAlertPayload{
+		Source:     source,
+		ResultCode: result.ResultCode,
+		ResultDesc: result.ResultDesc,
+	}
+
+	tx, err := h.repo.GetByOriginatorConversationID(ctx, result.OriginatorConversationID)
+	if errors.Is(err, storage.ErrNotFound) {
+		alert.Reason = models.SecurityAlertUnknownTransaction
+		raiseSecurityAlert(h.hub, correlation, alert)
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Failed to load B2C transaction %s: %v", result.OriginatorConversationID, err)
+		return nil, err
+	}
+
+	if alert.Mismatches = services.CrossCheckB2C(tx, result.ResultCode, result.GetResultParametersMap()); len(alert.Mismatches) > 0 {
+		alert.Reason = models.SecurityAlertCallbackMismatch
+		flagTransaction(ctx, h.repo, tx, alert)
+		raiseSecurityAlert(h.hub, paymentCorrelation(tx, result.ConversationID, result.OriginatorConversationID, result.TransactionID), alert)
+		return nil, nil
+	}
+	return tx, nil
+}
+
+// recordResult settles a pending payment with its outcome. It returns false
+// if the payment was already settled, by an earlier result or a timeout.
+func (h *B2CHandler) recordResult(ctx context.Context, tx *models.Transaction, result *models.B2CCallback, status models.TransactionStatus) bool {
+	settled, err := h.repo.UpdateStatusIf(ctx, tx.ID, models.TransactionStatusPending, status)
+	if err != nil {
+		log.Printf("Failed to settle B2C transaction %s: %v", result.OriginatorConversationID, err)
+		return false
+	}
+	if !settled {
+		log.Printf("B2C transaction %s already settled, ignoring outcome %s", result.OriginatorConversationID, status)
+		return false
+	}
+	tx.SetResult(status, result.ResultCode, result.ResultDesc)
+	if tx.ConversationID == "" {
+		tx.ConversationID = result.ConversationID
+	}
+	if result.TransactionID != "" {
+		tx.ReceiptNumber = result.TransactionID
+	}
+	if params := result.GetResultParametersMap(); len(params) > 0 {
+		if tx.Metadata == nil {
+			tx.Metadata = make(map[string]interface{})
+		}
+		tx.Metadata["result_parameters"] = params
+	}
+
+	if err := h.repo.Update(ctx, tx); err != nil {
+		log.Printf("Failed to update B2C transaction %s: %v", result.OriginatorConversationID, err)
+	}
+	return true
 }
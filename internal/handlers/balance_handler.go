@@ -0,0 +1,136 @@
+// ==========================
+// internal/handlers/balance_handler.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/services"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BalanceHandler struct {
+	config         *config.Config
+	balanceService *services.AccountBalanceService
+}
+
+func NewBalanceHandler(cfg *config.Config, balanceService *services.AccountBalanceService) *BalanceHandler {
+	return &BalanceHandler{
+		config:         cfg,
+		balanceService: balanceService,
+	}
+}
+
+func (h *BalanceHandler) GetBalance(c *gin.Context) {
+	snapshot, err := h.balanceService.Snapshot(c.Request.Context())
+	if err != nil {
+		log.Printf("Failed to load balance snapshot: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to load balance snapshot",
+			ErrorCode: "STORAGE_ERROR",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if snapshot == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:     "No balance snapshot yet",
+			ErrorCode: "BALANCE_UNKNOWN",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Account balance retrieved",
+		Data: map[string]interface{}{
+			"accounts":     snapshot.Accounts,
+			"completed_at": snapshot.CompletedAt,
+			"retrieved_at": snapshot.RetrievedAt,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *BalanceHandler) RefreshBalance(c *gin.Context) {
+	resp, err := h.balanceService.RequestBalance(c.Request.Context())
+	if err != nil {
+		log.Printf("Account balance query error: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to query account balance",
+			ErrorCode: "BALANCE_QUERY_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse{
+		Message: "Account balance query submitted",
+		Data: map[string]interface{}{
+			"conversation_id":            resp.ConversationID,
+			"originator_conversation_id": resp.OriginatorConversationID,
+			"response_code":              resp.ResponseCode,
+			"response_description":       resp.ResponseDescription,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *BalanceHandler) HandleResult(c *gin.Context) {
+	var callbackReq models.AccountBalanceResultRequest
+
+	if err := c.ShouldBindJSON(&callbackReq); err != nil {
+		log.Printf("Account balance result binding error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid callback data"})
+		return
+	}
+
+	result := callbackReq.Result
+
+	log.Printf("Account balance result - ConversationID: %s, ResultCode: %d, ResultDesc: %s",
+		result.ConversationID, result.ResultCode, result.ResultDesc)
+
+	if result.ResultCode == 0 {
+		params := result.GetResultParametersMap()
+
+		accounts, err := models.ParseAccountBalances(fmt.Sprintf("%v", params["AccountBalance"]))
+		if err != nil {
+			log.Printf("Failed to parse account balance: %v", err)
+		} else {
+			snapshot := &models.BalanceSnapshot{
+				Accounts:    accounts,
+				RetrievedAt: time.Now(),
+			}
+			if completedAt, ok := params["BOCompletedTime"]; ok {
+				snapshot.CompletedAt = fmt.Sprintf("%v", completedAt)
+			}
+			if err := h.balanceService.SetSnapshot(c.Request.Context(), snapshot); err != nil {
+				log.Printf("Failed to store balance snapshot: %v", err)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+}
+
+func (h *BalanceHandler) HandleTimeout(c *gin.Context) {
+	var callbackReq models.AccountBalanceResultRequest
+
+	if err := c.ShouldBindJSON(&callbackReq); err != nil {
+		log.Printf("Account balance timeout binding error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timeout data"})
+		return
+	}
+
+	log.Printf("Account balance timeout - ConversationID: %s, ResultDesc: %s",
+		callbackReq.Result.ConversationID, callbackReq.Result.ResultDesc)
+
+	c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+}
@@ -0,0 +1,145 @@
+// ==========================
+// internal/handlers/c2b_handler.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
+	ws "awesomeProject/internal/websocket"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type C2BHandler struct {
+	config *config.Config
+	rules  []services.C2BRule
+	repo   storage.TransactionRepository
+	hub    *ws.Hub
+}
+
+func NewC2BHandler(cfg *config.Config, rules []services.C2BRule, repo storage.TransactionRepository, hub *ws.Hub) *C2BHandler {
+	return &C2BHandler{
+		config: cfg,
+		rules:  rules,
+		repo:   repo,
+		hub:    hub,
+	}
+}
+
+// HandleValidation lets us accept or reject a paybill payment before M-Pesa completes it
+func (h *C2BHandler) HandleValidation(c *gin.Context) {
+	var payment models.C2BPayment
+
+	if err := c.ShouldBindJSON(&payment); err != nil {
+		log.Printf("C2B validation binding error: %v", err)
+		c.JSON(http.StatusOK, models.C2BValidationResponse{
+			ResultCode: models.C2BResultOtherError,
+			ResultDesc: "Rejected",
+		})
+		return
+	}
+
+	log.Printf("C2B Validation - TransID: %s, Amount: %s, BillRef: %s",
+		payment.TransID, payment.TransAmount, payment.BillRefNumber)
+
+	for _, rule := range h.rules {
+		if rejection := rule.Validate(&payment); rejection != nil {
+			log.Printf("C2B payment %s rejected: %s", payment.TransID, rejection.Reason)
+			c.JSON(http.StatusOK, models.C2BValidationResponse{
+				ResultCode: rejection.ResultCode,
+				ResultDesc: "Rejected",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.C2BValidationResponse{
+		ResultCode: models.C2BResultAccepted,
+		ResultDesc: "Accepted",
+	})
+}
+
+// HandleConfirmation records a completed paybill payment
+func (h *C2BHandler) HandleConfirmation(c *gin.Context) {
+	var payment models.C2BPayment
+
+	if err := c.ShouldBindJSON(&payment); err != nil {
+		log.Printf("C2B confirmation binding error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid confirmation data"})
+		return
+	}
+
+	log.Printf("C2B Confirmation - TransID: %s, Amount: %s, BillRef: %s",
+		payment.TransID, payment.TransAmount, payment.BillRefNumber)
+
+	// M-Pesa retries confirmations it thinks we missed
+	_, err := h.repo.GetByReceiptNumber(c.Request.Context(), payment.TransID)
+	if err == nil {
+		log.Printf("C2B payment %s already recorded", payment.TransID)
+		c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+		return
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		// Refuse rather than risk recording it twice; M-Pesa retries
+		log.Printf("Failed to look up C2B payment %s: %v", payment.TransID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "Temporarily unavailable"})
+		return
+	}
+
+	amount, _ := strconv.ParseFloat(payment.TransAmount, 64)
+	resultCode := 0
+	tx := &models.Transaction{
+		ID:               uuid.New().String(),
+		Type:             models.TransactionTypeC2B,
+		Status:           models.TransactionStatusSuccess,
+		PhoneNumber:      payment.MSISDN,
+		Amount:           int(math.Round(amount)),
+		AccountReference: payment.BillRefNumber,
+		CommandID:        payment.TransactionType,
+		ReceiptNumber:    payment.TransID,
+		ResultCode:       &resultCode,
+		ResultDesc:       "Confirmed",
+		Metadata: map[string]interface{}{
+			"trans_amount":         payment.TransAmount,
+			"trans_time":           payment.TransTime,
+			"business_short_code":  payment.BusinessShortCode,
+			"invoice_number":       payment.InvoiceNumber,
+			"org_account_balance":  payment.OrgAccountBalance,
+			"third_party_trans_id": payment.ThirdPartyTransID,
+			"first_name":           payment.FirstName,
+			"middle_name":          payment.MiddleName,
+			"last_name":            payment.LastName,
+		},
+	}
+	if err := h.repo.Create(c.Request.Context(), tx); err != nil {
+		log.Printf("Failed to store C2B payment %s: %v", payment.TransID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"ResultCode": 1, "ResultDesc": "Temporarily unavailable"})
+		return
+	}
+
+	// Broadcast confirmation via WebSocket
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventC2BConfirmation,
+		models.EventCorrelation{
+			TransactionRef: tx.ID,
+			TransactionID:  payment.TransID,
+			PhoneNumber:    payment.MSISDN,
+		},
+		models.C2BConfirmationPayload{
+			TransactionType:  payment.TransactionType,
+			Amount:           payment.TransAmount,
+			AccountReference: payment.BillRefNumber,
+			FirstName:        payment.FirstName,
+			TransTime:        payment.TransTime,
+		}))
+
+	c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+}
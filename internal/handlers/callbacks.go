@@ -0,0 +1,61 @@
+// ==========================
+// internal/handlers/callbacks.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/middleware"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	ws "awesomeProject/internal/websocket"
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verifyCallbackRef checks that a callback posted to a per-transaction URL
+// is about the transaction the URL was issued for, looking that transaction up
+// with find(id). It answers 403 and returns false when it is not.
+func verifyCallbackRef(c *gin.Context, find func(context.Context, string) (*models.Transaction, error), id string) bool {
+	ref, ok := middleware.CallbackRef(c)
+	if !ok {
+		return true
+	}
+
+	tx, err := find(c.Request.Context(), id)
+	if err == nil && tx.ID == ref {
+		return true
+	}
+
+	log.Printf("Rejected callback on %s for %s: URL was issued for transaction %s", c.Request.URL.Path, id, ref)
+	c.JSON(http.StatusForbidden, models.ErrorResponse{
+		Error:     "Callback does not match its transaction",
+		ErrorCode: "CALLBACK_MISMATCH",
+		Timestamp: time.Now(),
+	})
+	return false
+}
+
+// raiseSecurityAlert broadcasts a callback that failed cross-checking as a
+// security_alert event, in place of the payment event it claimed to be
+func raiseSecurityAlert(hub *ws.Hub, correlation models.EventCorrelation, alert models.SecurityAlertPayload) {
+	log.Printf("⚠️ Security alert: %s %s (checkout %s, conversation %s): %+v", alert.Source, alert.Reason,
+		correlation.CheckoutRequestID, correlation.OriginatorConversationID, alert.Mismatches)
+	hub.BroadcastPaymentStatus(models.NewEvent(models.EventSecurityAlert, correlation, alert))
+}
+
+// flagTransaction records a failed cross-check on the transaction. Its
+// status is left alone, so reconciliation or a status query can still
+// establish what really happened.
+func flagTransaction(ctx context.Context, repo storage.TransactionRepository, tx *models.Transaction, alert models.SecurityAlertPayload) {
+	if tx.Metadata == nil {
+		tx.Metadata = make(map[string]interface{})
+	}
+	tx.Metadata["security_alert"] = alert
+	if err := repo.Update(ctx, tx); err != nil {
+		log.Printf("Failed to flag transaction %s: %v", tx.ID, err)
+	}
+}
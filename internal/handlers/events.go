@@ -0,0 +1,24 @@
+// ==========================
+// internal/handlers/events.go
+// ==========================
+package handlers
+
+import "awesomeProject/internal/models"
+
+// paymentCorrelation identifies a Daraja result event, adding what we stored
+// at initiation when the payment is known
+func paymentCorrelation(tx *models.Transaction, conversationID, originatorConversationID, transactionID string) models.EventCorrelation {
+	correlation := models.EventCorrelation{
+		ConversationID:           conversationID,
+		OriginatorConversationID: originatorConversationID,
+		TransactionID:            transactionID,
+	}
+	if tx != nil {
+		correlation.TransactionRef = tx.ID
+		correlation.PhoneNumber = tx.PhoneNumber
+		if correlation.TransactionID == "" {
+			correlation.TransactionID = tx.ReceiptNumber
+		}
+	}
+	return correlation
+}
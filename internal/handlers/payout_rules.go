@@ -0,0 +1,74 @@
+// ==========================
+// internal/handlers/payout_rules.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/middleware"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkPayoutRules locks payout and evaluates its rules. It answers 409 if
+// another payout holds the lock too long, 422 listing every rule payout
+// breaks, or 500 if the rules could not be evaluated; nothing was sent then,
+// so the request's idempotency key is freed. If payout may go ahead, it
+// returns true and a func the caller must call once payout is stored.
+func checkPayoutRules(c *gin.Context, rules *services.PayoutRules, payout *services.Payout) (func(), bool) {
+	release, err := rules.Lock(c.Request.Context(), payout)
+	if errors.Is(err, services.ErrPayoutBusy) {
+		middleware.ReleaseIdempotencyKey(c)
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:     "Another payout for this phone number or client is in progress",
+			ErrorCode: "PAYOUT_BUSY",
+			Timestamp: time.Now(),
+		})
+		return nil, false
+	}
+	if err != nil {
+		rulesCheckFailed(c, payout, err)
+		return nil, false
+	}
+
+	violations, err := rules.Evaluate(c.Request.Context(), payout)
+	if err != nil {
+		release()
+		rulesCheckFailed(c, payout, err)
+		return nil, false
+	}
+	if len(violations) == 0 {
+		return release, true
+	}
+	release()
+
+	middleware.ReleaseIdempotencyKey(c)
+	log.Printf("Refused %s payout of %d to %s for client %s: %+v",
+		payout.Type, payout.Amount, payout.PhoneNumber, payout.ClientID, violations)
+	c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+		Error:     "Payout refused by limits",
+		ErrorCode: "PAYOUT_RULE_VIOLATION",
+		Details: map[string]interface{}{
+			"amount":     payout.Amount,
+			"violations": violations,
+		},
+		Timestamp: time.Now(),
+	})
+	return nil, false
+}
+
+func rulesCheckFailed(c *gin.Context, payout *services.Payout, err error) {
+	middleware.ReleaseIdempotencyKey(c)
+	log.Printf("Failed to evaluate %s payout rules: %v", payout.Type, err)
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:     "Failed to check payout limits",
+		ErrorCode: "RULES_CHECK_FAILED",
+		Details:   map[string]interface{}{"error": err.Error()},
+		Timestamp: time.Now(),
+	})
+}
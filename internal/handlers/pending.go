@@ -0,0 +1,73 @@
+// ==========================
+// internal/handlers/pending.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/middleware"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storePending records tx before Daraja is asked to move money, so payout
+// caps count it at once and its callback always finds it. It answers 409 if
+// another transaction has its OriginatorConversationID, or 500, and returns
+// false if tx could not be stored; nothing must be sent then, so the
+// request's idempotency key is freed.
+func storePending(c *gin.Context, repo storage.TransactionRepository, tx *models.Transaction) bool {
+	tx.Status = models.TransactionStatusPending
+	err := repo.Create(c.Request.Context(), tx)
+	if err != nil {
+		middleware.ReleaseIdempotencyKey(c)
+	}
+	if errors.Is(err, storage.ErrDuplicate) {
+		duplicateTransaction(c, tx)
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to store %s transaction %s: %v", tx.Type, tx.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to record transaction",
+			ErrorCode: "STORAGE_ERROR",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return false
+	}
+	return true
+}
+
+// duplicateTransaction answers 409 for a transaction reusing another's
+// OriginatorConversationID; their callbacks could not be told apart
+func duplicateTransaction(c *gin.Context, tx *models.Transaction) {
+	c.JSON(http.StatusConflict, models.ErrorResponse{
+		Error:     "originator_conversation_id is already used by another transaction",
+		ErrorCode: "DUPLICATE_ORIGINATOR_CONVERSATION_ID",
+		Details:   map[string]interface{}{"originator_conversation_id": tx.OriginatorConversationID},
+		Timestamp: time.Now(),
+	})
+}
+
+// markFailed records that Daraja refused a transaction stored by storePending
+func markFailed(ctx context.Context, repo storage.TransactionRepository, tx *models.Transaction, cause error) {
+	tx.Status = models.TransactionStatusFailed
+	tx.ResultDesc = cause.Error()
+	if err := repo.Update(ctx, tx); err != nil {
+		log.Printf("Failed to mark %s transaction %s failed: %v", tx.Type, tx.ID, err)
+	}
+}
+
+// recordInitiated stores the identifiers Daraja returned for tx. The request
+// was already sent, so a failure is only logged.
+func recordInitiated(ctx context.Context, repo storage.TransactionRepository, tx *models.Transaction) {
+	if err := repo.Update(ctx, tx); err != nil {
+		log.Printf("Failed to update %s transaction %s after initiation: %v", tx.Type, tx.ID, err)
+	}
+}
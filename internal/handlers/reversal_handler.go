@@ -0,0 +1,316 @@
+// ==========================
+// internal/handlers/reversal_handler.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/middleware"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
+	ws "awesomeProject/internal/websocket"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ReversalHandler struct {
+	config          *config.Config
+	reversalService *services.ReversalService
+	statusService   *services.TransactionStatusService
+	repo            storage.TransactionRepository
+	hub             *ws.Hub
+}
+
+func NewReversalHandler(cfg *config.Config, reversalService *services.ReversalService, statusService *services.TransactionStatusService, repo storage.TransactionRepository, hub *ws.Hub) *ReversalHandler {
+	return &ReversalHandler{
+		config:          cfg,
+		reversalService: reversalService,
+		statusService:   statusService,
+		repo:            repo,
+		hub:             hub,
+	}
+}
+
+func (h *ReversalHandler) InitiateReversal(c *gin.Context) {
+	var req models.ReversalRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			ErrorCode: "INVALID_REQUEST",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	original, err := h.repo.GetByReceiptNumber(ctx, req.TransactionID)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:     "Transaction not found",
+			ErrorCode: "TRANSACTION_NOT_FOUND",
+			Details:   map[string]interface{}{"transaction_id": req.TransactionID},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to look up transaction %s for reversal: %v", req.TransactionID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to look up transaction",
+			ErrorCode: "STORAGE_ERROR",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Only money we received can be reversed
+	if original.Type != models.TransactionTypeSTK && original.Type != models.TransactionTypeC2B {
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+			Error:     "Only STK and C2B payments can be reversed",
+			ErrorCode: "NOT_REVERSIBLE",
+			Details:   map[string]interface{}{"type": original.Type},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Claim the transaction so it is reversed at most once
+	claimed, err := h.repo.UpdateStatusIf(ctx, original.ID, models.TransactionStatusSuccess, models.TransactionStatusReversing)
+	if err != nil {
+		log.Printf("Failed to claim transaction %s for reversal: %v", original.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to start reversal",
+			ErrorCode: "STORAGE_ERROR",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if !claimed {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:     "Transaction cannot be reversed in its current state",
+			ErrorCode: "ALREADY_REVERSED",
+			Details:   map[string]interface{}{"status": original.Status},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if req.Remarks == "" {
+		req.Remarks = "Reversal"
+	}
+
+	// Store the reversal first, so its result always finds it
+	reversal := &models.Transaction{
+		ID:          uuid.New().String(),
+		Type:        models.TransactionTypeReversal,
+		PhoneNumber: original.PhoneNumber,
+		Amount:      original.Amount,
+		ClientID:    middleware.ClientID(c),
+		Metadata: map[string]interface{}{
+			"original_id":      original.ID,
+			"original_receipt": original.ReceiptNumber,
+			"remarks":          req.Remarks,
+		},
+	}
+	if !storePending(c, h.repo, reversal) {
+		settleOriginal(ctx, h.repo, original.ID, false)
+		return
+	}
+
+	resp, err := h.reversalService.Reverse(ctx, original.ReceiptNumber, original.Amount, req.Remarks, req.Occasion, reversal.ID)
+	if err != nil {
+		log.Printf("Reversal error for %s: %v", original.ReceiptNumber, err)
+		markFailed(ctx, h.repo, reversal, err)
+		settleOriginal(ctx, h.repo, original.ID, false)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to initiate reversal",
+			ErrorCode: "REVERSAL_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	reversal.ConversationID = resp.ConversationID
+	reversal.OriginatorConversationID = resp.OriginatorConversationID
+	recordInitiated(ctx, h.repo, reversal)
+
+	// Broadcast initiation via WebSocket
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventReversalInitiated,
+		paymentCorrelation(reversal, resp.ConversationID, resp.OriginatorConversationID, ""),
+		models.ReversalPayload{
+			Status:                models.TransactionStatusPending,
+			Amount:                original.Amount,
+			OriginalTransactionID: original.ReceiptNumber,
+		}))
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Reversal initiated successfully",
+		Data: map[string]interface{}{
+			"conversation_id":            resp.ConversationID,
+			"originator_conversation_id": resp.OriginatorConversationID,
+			"response_code":              resp.ResponseCode,
+			"response_description":       resp.ResponseDescription,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *ReversalHandler) HandleResult(c *gin.Context) {
+	var callbackReq models.ReversalResultRequest
+
+	if err := c.ShouldBindJSON(&callbackReq); err != nil {
+		log.Printf("Reversal result binding error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid callback data"})
+		return
+	}
+
+	result := callbackReq.Result
+	if !verifyCallbackRef(c, h.repo.GetByOriginatorConversationID, result.OriginatorConversationID) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	log.Printf("Reversal result - ConversationID: %s, ResultCode: %d, ResultDesc: %s",
+		result.ConversationID, result.ResultCode, result.ResultDesc)
+
+	status := models.TransactionStatusSuccess
+	if result.ResultCode != 0 {
+		status = models.TransactionStatusFailed
+	}
+
+	reversal := h.recordResult(ctx, &result, status)
+	if reversal != nil {
+		originalID, _ := reversal.Metadata["original_id"].(string)
+		settleOriginal(ctx, h.repo, originalID, status == models.TransactionStatusSuccess)
+	}
+
+	// Broadcast reversal outcome via WebSocket
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventReversalResult,
+		paymentCorrelation(reversal, result.ConversationID, result.OriginatorConversationID, result.TransactionID),
+		reversalPayload(reversal, status, &result)))
+
+	c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+}
+
+func (h *ReversalHandler) HandleTimeout(c *gin.Context) {
+	var callbackReq models.ReversalResultRequest
+
+	if err := c.ShouldBindJSON(&callbackReq); err != nil {
+		log.Printf("Reversal timeout binding error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timeout data"})
+		return
+	}
+
+	result := callbackReq.Result
+	if !verifyCallbackRef(c, h.repo.GetByOriginatorConversationID, result.OriginatorConversationID) {
+		return
+	}
+
+	log.Printf("Reversal timeout - ConversationID: %s, ResultDesc: %s",
+		result.ConversationID, result.ResultDesc)
+
+	// The original stays REVERSING: whether M-Pesa reversed it is unknown.
+	// The status query's result settles it, see TransactionStatusHandler.
+	ctx := c.Request.Context()
+	reversal := h.recordResult(ctx, &result, models.TransactionStatusTimeout)
+	if reversal != nil {
+		h.queryOutcome(ctx, reversal)
+	}
+
+	// Broadcast timeout via WebSocket
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventReversalTimeout,
+		paymentCorrelation(reversal, result.ConversationID, result.OriginatorConversationID, ""),
+		reversalPayload(reversal, models.TransactionStatusTimeout, &result)))
+
+	c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+}
+
+func (h *ReversalHandler) recordResult(ctx context.Context, result *models.ReversalCallback, status models.TransactionStatus) *models.Transaction {
+	reversal, err := h.repo.GetByOriginatorConversationID(ctx, result.OriginatorConversationID)
+	if err != nil {
+		log.Printf("No stored reversal for %s: %v", result.OriginatorConversationID, err)
+		return nil
+	}
+
+	reversal.SetResult(status, result.ResultCode, result.ResultDesc)
+	if result.TransactionID != "" {
+		reversal.ReceiptNumber = result.TransactionID
+	}
+	if params := result.GetResultParametersMap(); len(params) > 0 {
+		if reversal.Metadata == nil {
+			reversal.Metadata = make(map[string]interface{})
+		}
+		reversal.Metadata["result_parameters"] = params
+	}
+
+	if err := h.repo.Update(ctx, reversal); err != nil {
+		log.Printf("Failed to update reversal %s: %v", result.OriginatorConversationID, err)
+	}
+	return reversal
+}
+
+// reversalPayload describes a reversal outcome, including the original
+// receipt it was reversing when the reversal is known
+func reversalPayload(reversal *models.Transaction, status models.TransactionStatus, result *models.ReversalCallback) models.ReversalPayload {
+	payload := models.ReversalPayload{
+		Status:           status,
+		ResultCode:       result.ResultCode,
+		ResultDesc:       result.ResultDesc,
+		ResultParameters: result.GetResultParametersMap(),
+	}
+	if reversal != nil {
+		payload.Amount = reversal.Amount
+		payload.OriginalTransactionID, _ = reversal.Metadata["original_receipt"].(string)
+	}
+	return payload
+}
+
+// queryOutcome asks M-Pesa what became of a reversal that timed out. If the
+// query cannot be sent, the original stays REVERSING until the reversal is
+// queried through the transaction status endpoint.
+func (h *ReversalHandler) queryOutcome(ctx context.Context, reversal *models.Transaction) {
+	req := &models.TransactionStatusRequest{
+		OriginatorConversationID: reversal.OriginatorConversationID,
+		Remarks:                  "Reversal outcome",
+	}
+	query := newStatusQuery(req, reversal, reversal.ClientID)
+	query.Status = models.TransactionStatusPending
+	if err := h.repo.Create(ctx, query); err != nil {
+		log.Printf("Failed to store outcome query of reversal %s: %v", reversal.OriginatorConversationID, err)
+		return
+	}
+
+	resp, err := h.statusService.Query(ctx, req, query.ID)
+	if err != nil {
+		log.Printf("Failed to query outcome of reversal %s: %v", reversal.OriginatorConversationID, err)
+		markFailed(ctx, h.repo, query, err)
+		return
+	}
+	query.ConversationID = resp.ConversationID
+	query.OriginatorConversationID = resp.OriginatorConversationID
+	recordInitiated(ctx, h.repo, query)
+}
+
+// settleOriginal finishes the reversal of a transaction: it becomes REVERSED
+// if the reversal went through, or is handed back so the reversal can be
+// attempted again
+func settleOriginal(ctx context.Context, repo storage.TransactionRepository, id string, reversed bool) {
+	to := models.TransactionStatusSuccess
+	if reversed {
+		to = models.TransactionStatusReversed
+	}
+	if _, err := repo.UpdateStatusIf(ctx, id, models.TransactionStatusReversing, to); err != nil {
+		log.Printf("Failed to move transaction %s from %s to %s: %v", id, models.TransactionStatusReversing, to, err)
+	}
+}
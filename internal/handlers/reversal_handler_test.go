@@ -0,0 +1,67 @@
+// ==========================
+// internal/handlers/reversal_handler_test.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"context"
+	"testing"
+)
+
+// storedPayment returns a repository holding one STK payment, "tx-1"
+func storedPayment(t *testing.T, status models.TransactionStatus) *storage.MemoryRepository {
+	t.Helper()
+	repo := storage.NewMemoryRepository()
+	if err := repo.Create(context.Background(), &models.Transaction{ID: "tx-1", Type: models.TransactionTypeSTK, Status: status}); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func assertStatus(t *testing.T, repo storage.TransactionRepository, want models.TransactionStatus) {
+	t.Helper()
+	tx, err := repo.GetByID(context.Background(), "tx-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Status != want {
+		t.Errorf("status = %s, want %s", tx.Status, want)
+	}
+}
+
+func TestReversalClaimsPaymentOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := storedPayment(t, models.TransactionStatusSuccess)
+
+	// The claim InitiateReversal makes before calling Daraja
+	for i, want := range []bool{true, false} {
+		claimed, err := repo.UpdateStatusIf(ctx, "tx-1", models.TransactionStatusSuccess, models.TransactionStatusReversing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claimed != want {
+			t.Errorf("claim %d = %v, want %v", i+1, claimed, want)
+		}
+	}
+	assertStatus(t, repo, models.TransactionStatusReversing)
+}
+
+func TestSettleOriginalReversed(t *testing.T) {
+	repo := storedPayment(t, models.TransactionStatusReversing)
+	settleOriginal(context.Background(), repo, "tx-1", true)
+	assertStatus(t, repo, models.TransactionStatusReversed)
+}
+
+func TestSettleOriginalHandsBackFailedReversal(t *testing.T) {
+	repo := storedPayment(t, models.TransactionStatusReversing)
+	settleOriginal(context.Background(), repo, "tx-1", false)
+	assertStatus(t, repo, models.TransactionStatusSuccess)
+}
+
+func TestSettleOriginalIgnoresSettledPayment(t *testing.T) {
+	repo := storedPayment(t, models.TransactionStatusReversed)
+	settleOriginal(context.Background(), repo, "tx-1", false)
+	assertStatus(t, repo, models.TransactionStatusReversed)
+}
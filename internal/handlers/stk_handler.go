@@ -5,34 +5,42 @@ package handlers
 
 import (
 	"awesomeProject/internal/config"
+	"awesomeProject/internal/daraja"
+	"awesomeProject/internal/middleware"
 	"awesomeProject/internal/models"
 	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
 	"awesomeProject/internal/utils"
 	"awesomeProject/internal/websocket"
 	"log"
 
 	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type STKHandler struct {
-	config      *config.Config
-	authService *services.AuthService
-	hub         *websocket.Hub
+	config     *config.Config
+	stkService *services.STKService
+	rules      *services.PayoutRules
+	repo       storage.TransactionRepository
+	hub        *websocket.Hub
 }
 
-func NewSTKHandler(cfg *config.Config, authSvc *services.AuthService, hub *websocket.Hub) *STKHandler {
+func NewSTKHandler(cfg *config.Config, stkSvc *services.STKService, rules *services.PayoutRules, repo storage.TransactionRepository, hub *websocket.Hub) *STKHandler {
 	return &STKHandler{
-		config:      cfg,
-		authService: authSvc,
-		hub:         hub,
+		config:     cfg,
+		stkService: stkSvc,
+		rules:      rules,
+		repo:       repo,
+		hub:        hub,
 	}
 }
 
@@ -60,26 +68,6 @@ func (h *STKHandler) InitiateSTKPush(c *gin.Context) {
 	log.Printf("📋 Parsed STK Request - Phone: %s, Amount: %d, Account: %s, Desc: %s",
 		req.PhoneNumber, req.Amount, req.AccountReference, req.TransactionDesc)
 
-	accessToken, err := h.authService.GetAccessToken(false)
-	if err != nil {
-		log.Printf("❌ Failed to get access token: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:     "Failed to get access token",
-			Details:   map[string]interface{}{"error": err.Error()},
-			Timestamp: time.Now(),
-		})
-		return
-	}
-
-	log.Printf("🔑 Access token obtained successfully")
-
-	timestamp := utils.GetTimestamp()
-	password := utils.GeneratePassword(
-		strconv.Itoa(h.config.BusinessShortCode),
-		h.config.Passkey,
-		timestamp,
-	)
-
 	phoneNumber, err := utils.FormatPhoneNumber(req.PhoneNumber)
 	if err != nil {
 		log.Printf("❌ Phone number formatting failed: %v", err)
@@ -92,79 +80,66 @@ func (h *STKHandler) InitiateSTKPush(c *gin.Context) {
 
 	log.Printf("📱 Formatted phone number: %s", phoneNumber)
 
-	payload := map[string]interface{}{
-		"BusinessShortCode": h.config.BusinessShortCode,
-		"Password":          password,
-		"Timestamp":         timestamp,
-		"TransactionType":   "CustomerPayBillOnline",
-		"Amount":            req.Amount,
-		"PartyA":            phoneNumber,
-		"PartyB":            h.config.BusinessShortCode,
-		"PhoneNumber":       phoneNumber,
-		"CallBackURL":       h.config.STKCallbackURL,
-		"AccountReference":  req.AccountReference,
-		"TransactionDesc":   req.TransactionDesc,
-	}
-
-	// Log the payload being sent to Safaricom (excluding password for security)
-	logPayload := make(map[string]interface{})
-	for k, v := range payload {
-		if k != "Password" {
-			logPayload[k] = v
-		} else {
-			logPayload[k] = "***REDACTED***"
-		}
+	release, ok := checkPayoutRules(c, h.rules, &services.Payout{
+		Type:        models.TransactionTypeSTK,
+		PhoneNumber: phoneNumber,
+		Amount:      req.Amount,
+		CommandID:   services.STKTransactionType,
+		ClientID:    middleware.ClientID(c),
+	})
+	if !ok {
+		return
 	}
-	logPayloadJSON, _ := json.MarshalIndent(logPayload, "", "  ")
-	log.Printf("📤 Sending STK Push to Safaricom:\n%s", string(logPayloadJSON))
-
-	jsonPayload, _ := json.Marshal(payload)
-	httpReq, _ := http.NewRequest("POST", h.config.STKPushURL(), bytes.NewBuffer(jsonPayload))
-	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	log.Printf("🌐 M-Pesa API URL: %s", h.config.STKPushURL())
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	startTime := time.Now()
-	resp, err := client.Do(httpReq)
-	duration := time.Since(startTime)
+	tx := &models.Transaction{
+		ID:               uuid.New().String(),
+		Type:             models.TransactionTypeSTK,
+		PhoneNumber:      phoneNumber,
+		Amount:           req.Amount,
+		AccountReference: req.AccountReference,
+		ClientID:         middleware.ClientID(c),
+		Metadata:         map[string]interface{}{"transaction_desc": req.TransactionDesc},
+	}
+	stored := storePending(c, h.repo, tx)
+	release()
+	if !stored {
+		return
+	}
 
+	result, err := h.stkService.InitiatePush(c.Request.Context(), &req, phoneNumber, tx.ID)
 	if err != nil {
-		log.Printf("❌ HTTP request failed after %v: %v", duration, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:     "Failed to initiate STK push",
-			Details:   map[string]interface{}{"error": err.Error()},
+		markFailed(c.Request.Context(), h.repo, tx, err)
+	}
+	var apiErr *daraja.APIError
+	if errors.As(err, &apiErr) {
+		log.Printf("❌ STK Push failed - Status: %d, Response: %v", apiErr.StatusCode, apiErr.Details())
+		c.JSON(apiErr.StatusCode, models.ErrorResponse{
+			Error:     "STK push failed",
+			ErrorCode: apiErr.ErrorCode,
+			Details:   apiErr.Details(),
 			Timestamp: time.Now(),
 		})
 		return
 	}
-	defer resp.Body.Close()
-
-	log.Printf("⏱️  M-Pesa API responded in %v with status: %d", duration, resp.StatusCode)
-
-	body, _ := io.ReadAll(resp.Body)
-	log.Printf("📨 M-Pesa API Response: %s", string(body))
-
-	if resp.StatusCode != http.StatusOK {
-		var errorData map[string]interface{}
-		json.Unmarshal(body, &errorData)
-		log.Printf("❌ STK Push failed - Status: %d, Response: %v", resp.StatusCode, errorData)
-		c.JSON(resp.StatusCode, models.ErrorResponse{
-			Error:     "STK push failed",
-			ErrorCode: fmt.Sprintf("%v", errorData["errorCode"]),
-			Details:   errorData,
+	if err != nil {
+		log.Printf("❌ STK Push request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to initiate STK push",
+			Details:   map[string]interface{}{"error": err.Error()},
 			Timestamp: time.Now(),
 		})
 		return
 	}
 
-	var result models.STKPushResponse
-	json.Unmarshal(body, &result)
-
 	log.Printf("✅ STK Push initiated successfully - CheckoutRequestID: %s, MerchantRequestID: %s",
 		result.CheckoutRequestID, result.MerchantRequestID)
 
+	// The reconciler queries Daraja for pending pushes if the callback never
+	// reaches us
+	tx.MerchantRequestID = result.MerchantRequestID
+	tx.CheckoutRequestID = result.CheckoutRequestID
+	recordInitiated(c.Request.Context(), h.repo, tx)
+
 	c.JSON(http.StatusOK, result)
 }
 
@@ -180,21 +155,166 @@ func (h *STKHandler) STKPushCallback(c *gin.Context) {
 	}
 
 	callback := req.Body.STKCallback
+	if !verifyCallbackRef(c, h.repo.GetByCheckoutRequestID, callback.CheckoutRequestID) {
+		return
+	}
 
-	// Broadcast to WebSocket clients
-	h.hub.BroadcastPaymentStatus(map[string]interface{}{
-		"type": "stk_callback",
-		"data": callback,
+	// A callback we could not verify is left for the reconciler to settle
+	if tx, ok := h.checkCallback(c.Request.Context(), callback, false); ok {
+		h.applyCallback(c.Request.Context(), tx, callback)
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message:   "Callback received successfully",
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *STKHandler) QueryStatus(c *gin.Context) {
+	checkoutRequestID := c.Param("checkoutRequestID")
+
+	result, err := h.stkService.QueryStatus(c.Request.Context(), checkoutRequestID)
+	if err != nil {
+		log.Printf("❌ STK status query failed for %s: %v", checkoutRequestID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to query STK push status",
+			ErrorCode: "STK_QUERY_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	log.Printf("🔎 STK status for %s: %s (%d %s)",
+		checkoutRequestID, result.Status, result.ResultCode, result.ResultDesc)
+
+	// Only resolved outcomes are worth telling clients about. ApplyCallback
+	// broadcasts them as stk_callback, once, if they settle the push.
+	if result.Status.IsFinal() {
+		h.ApplyCallback(result.ToCallback())
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "STK push status retrieved",
+		Data: map[string]interface{}{
+			"merchant_request_id": result.MerchantRequestID,
+			"checkout_request_id": result.CheckoutRequestID,
+			"status":              result.Status,
+			"result_code":         result.ResultCode,
+			"result_desc":         result.ResultDesc,
+		},
+		Timestamp: time.Now(),
 	})
+}
+
+// ApplyCallback records an STK Push outcome we queried from Daraja ourselves,
+// as the reconciler and QueryStatus do. Queried outcomes carry no
+// CallbackMetadata, so only the push they are about is checked.
+func (h *STKHandler) ApplyCallback(callback models.STKCallback) {
+	ctx := context.Background()
+	if tx, ok := h.checkCallback(ctx, callback, true); ok {
+		h.applyCallback(ctx, tx, callback)
+	}
+}
+
+// checkCallback finds the push a callback is about and, unless it was
+// queried, cross-checks the callback against it. It raises a security alert
+// and returns false when the push is unknown or the callback disagrees with it.
+func (h *STKHandler) checkCallback(ctx context.Context, callback models.STKCallback, queried bool) (*models.Transaction, bool) {
+	correlation := models.EventCorrelation{
+		CheckoutRequestID: callback.CheckoutRequestID,
+		MerchantRequestID: callback.MerchantRequestID,
+	}
+	alert := models.SecurityAlertPayload{
+		Source:     models.EventSTKCallback,
+		ResultCode: callback.ResultCode,
+		ResultDesc: callback.ResultDesc,
+	}
+
+	tx, err := h.repo.GetByCheckoutRequestID(ctx, callback.CheckoutRequestID)
+	if errors.Is(err, storage.ErrNotFound) {
+		alert.Reason = models.SecurityAlertUnknownTransaction
+		raiseSecurityAlert(h.hub, correlation, alert)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("❌ Failed to load STK transaction %s: %v", callback.CheckoutRequestID, err)
+		return nil, false
+	}
+
+	if queried {
+		return tx, true
+	}
+	if alert.Mismatches = services.CrossCheckSTK(tx, callback.ResultCode, callback.GetMetadataMap()); len(alert.Mismatches) > 0 {
+		alert.Reason = models.SecurityAlertCallbackMismatch
+		correlation.TransactionRef = tx.ID
+		correlation.PhoneNumber = tx.PhoneNumber
+		flagTransaction(ctx, h.repo, tx, alert)
+		raiseSecurityAlert(h.hub, correlation, alert)
+		return nil, false
+	}
+	return tx, true
+}
+
+// applyCallback records and broadcasts the outcome of a push, unless another
+// callback or the reconciler settled it first
+func (h *STKHandler) applyCallback(ctx context.Context, tx *models.Transaction, callback models.STKCallback) {
+	if !h.recordCallback(ctx, tx, callback) {
+		return
+	}
+
+	// Broadcast to WebSocket clients
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventSTKCallback,
+		models.EventCorrelation{
+			TransactionRef:    tx.ID,
+			CheckoutRequestID: callback.CheckoutRequestID,
+			MerchantRequestID: callback.MerchantRequestID,
+			TransactionID:     tx.ReceiptNumber,
+			PhoneNumber:       tx.PhoneNumber,
+		},
+		models.STKResultPayload{
+			Status:     models.STKStatusFromResultCode(callback.ResultCode),
+			ResultCode: callback.ResultCode,
+			ResultDesc: callback.ResultDesc,
+			Amount:     tx.Amount,
+			Metadata:   callback.GetMetadataMap(),
+		}))
 
 	if callback.ResultCode == 0 {
 		fmt.Printf("✓ STK Push successful: %s\n", callback.CheckoutRequestID)
 	} else {
 		fmt.Printf("✗ STK Push failed: %s\n", callback.ResultDesc)
 	}
+}
 
-	c.JSON(http.StatusOK, models.SuccessResponse{
-		Message:   "Callback received successfully",
-		Timestamp: time.Now(),
-	})
+// recordCallback settles a pending push with its outcome. It returns false if
+// the push was already settled.
+func (h *STKHandler) recordCallback(ctx context.Context, tx *models.Transaction, callback models.STKCallback) bool {
+	status := models.TransactionStatus(models.STKStatusFromResultCode(callback.ResultCode))
+	settled, err := h.repo.UpdateStatusIf(ctx, tx.ID, models.TransactionStatusPending, status)
+	if err != nil {
+		log.Printf("❌ Failed to settle STK transaction %s: %v", callback.CheckoutRequestID, err)
+		return false
+	}
+	if !settled {
+		log.Printf("STK transaction %s already settled, ignoring outcome %s", callback.CheckoutRequestID, status)
+		return false
+	}
+	tx.SetResult(status, callback.ResultCode, callback.ResultDesc)
+
+	metadata := callback.GetMetadataMap()
+	if receipt, ok := metadata["MpesaReceiptNumber"]; ok {
+		tx.ReceiptNumber = fmt.Sprintf("%v", receipt)
+	}
+	if len(metadata) > 0 {
+		if tx.Metadata == nil {
+			tx.Metadata = make(map[string]interface{})
+		}
+		tx.Metadata["callback_metadata"] = metadata
+	}
+
+	if err := h.repo.Update(ctx, tx); err != nil {
+		log.Printf("❌ Failed to update STK transaction %s: %v", callback.CheckoutRequestID, err)
+	}
+	return true
 }
@@ -0,0 +1,290 @@
+// ==========================
+// internal/handlers/transaction_status_handler.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/middleware"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
+	ws "awesomeProject/internal/websocket"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TransactionStatusHandler struct {
+	config        *config.Config
+	statusService *services.TransactionStatusService
+	repo          storage.TransactionRepository
+	hub           *ws.Hub
+}
+
+func NewTransactionStatusHandler(cfg *config.Config, statusService *services.TransactionStatusService, repo storage.TransactionRepository, hub *ws.Hub) *TransactionStatusHandler {
+	return &TransactionStatusHandler{
+		config:        cfg,
+		statusService: statusService,
+		repo:          repo,
+		hub:           hub,
+	}
+}
+
+func (h *TransactionStatusHandler) QueryStatus(c *gin.Context) {
+	var req models.TransactionStatusRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			ErrorCode: "INVALID_REQUEST",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if req.TransactionID == "" && req.OriginatorConversationID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "transaction_id or originator_conversation_id is required",
+			ErrorCode: "INVALID_REQUEST",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if req.Remarks == "" {
+		req.Remarks = "Status query"
+	}
+
+	// The transaction being queried, if we know it
+	target, err := h.findTarget(c.Request.Context(), &req)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Failed to look up transaction for status query: %v", err)
+	}
+
+	// Store the query first, so its result always finds it
+	query := newStatusQuery(&req, target, middleware.ClientID(c))
+	if !storePending(c, h.repo, query) {
+		return
+	}
+
+	resp, err := h.statusService.Query(c.Request.Context(), &req, query.ID)
+	if err != nil {
+		log.Printf("Transaction status query error: %v", err)
+		markFailed(c.Request.Context(), h.repo, query, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to query transaction status",
+			ErrorCode: "STATUS_QUERY_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	query.ConversationID = resp.ConversationID
+	query.OriginatorConversationID = resp.OriginatorConversationID
+	recordInitiated(c.Request.Context(), h.repo, query)
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Transaction status query submitted",
+		Data: map[string]interface{}{
+			"conversation_id":            resp.ConversationID,
+			"originator_conversation_id": resp.OriginatorConversationID,
+			"response_code":              resp.ResponseCode,
+			"response_description":       resp.ResponseDescription,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *TransactionStatusHandler) HandleResult(c *gin.Context) {
+	var callbackReq models.TransactionStatusResultRequest
+
+	if err := c.ShouldBindJSON(&callbackReq); err != nil {
+		log.Printf("Transaction status result binding error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid callback data"})
+		return
+	}
+
+	result := callbackReq.Result
+	if !verifyCallbackRef(c, h.repo.GetByOriginatorConversationID, result.OriginatorConversationID) {
+		return
+	}
+
+	params := result.GetResultParametersMap()
+
+	log.Printf("Transaction status result - ConversationID: %s, ResultCode: %d, ResultDesc: %s",
+		result.ConversationID, result.ResultCode, result.ResultDesc)
+
+	ctx := c.Request.Context()
+	queryStatus := models.TransactionStatusSuccess
+	if result.ResultCode != 0 {
+		queryStatus = models.TransactionStatusFailed
+	}
+	query := h.completeQuery(ctx, result.OriginatorConversationID, queryStatus, result.ResultCode, result.ResultDesc)
+
+	mpesaStatus := fmt.Sprintf("%v", params["TransactionStatus"])
+	payload := models.TransactionStatusPayload{
+		ResultCode:        result.ResultCode,
+		ResultDesc:        result.ResultDesc,
+		TransactionStatus: mpesaStatus,
+		ResultParameters:  params,
+	}
+
+	target := h.queryTarget(ctx, query, params)
+	if target != nil {
+		if status, ok := models.TransactionStatusFromMpesa(mpesaStatus); ok && result.ResultCode == 0 {
+			if h.updateTarget(ctx, target, status, params) {
+				payload.Status = status
+			}
+		}
+	}
+
+	// Broadcast final state via WebSocket
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventTransactionStatusResult,
+		paymentCorrelation(target, result.ConversationID, result.OriginatorConversationID, ""), payload))
+
+	c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+}
+
+func (h *TransactionStatusHandler) HandleTimeout(c *gin.Context) {
+	var callbackReq models.TransactionStatusResultRequest
+
+	if err := c.ShouldBindJSON(&callbackReq); err != nil {
+		log.Printf("Transaction status timeout binding error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timeout data"})
+		return
+	}
+
+	result := callbackReq.Result
+	if !verifyCallbackRef(c, h.repo.GetByOriginatorConversationID, result.OriginatorConversationID) {
+		return
+	}
+
+	log.Printf("Transaction status timeout - ConversationID: %s, ResultDesc: %s",
+		result.ConversationID, result.ResultDesc)
+
+	h.completeQuery(c.Request.Context(), result.OriginatorConversationID,
+		models.TransactionStatusTimeout, result.ResultCode, result.ResultDesc)
+
+	// Broadcast timeout via WebSocket
+	h.hub.BroadcastPaymentStatus(models.NewEvent(models.EventTransactionStatusTimeout,
+		models.EventCorrelation{
+			ConversationID:           result.ConversationID,
+			OriginatorConversationID: result.OriginatorConversationID,
+		},
+		models.TransactionStatusPayload{
+			ResultCode: result.ResultCode,
+			ResultDesc: result.ResultDesc,
+			Status:     models.TransactionStatusTimeout,
+		}))
+
+	c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+}
+
+// newStatusQuery is the record of a status query, stored before it is sent
+// so its asynchronous result can find the transaction it is about
+func newStatusQuery(req *models.TransactionStatusRequest, target *models.Transaction, clientID string) *models.Transaction {
+	metadata := map[string]interface{}{
+		"query_transaction_id":             req.TransactionID,
+		"query_originator_conversation_id": req.OriginatorConversationID,
+	}
+	if target != nil {
+		metadata["target_id"] = target.ID
+	}
+	return &models.Transaction{
+		ID:       uuid.New().String(),
+		Type:     models.TransactionTypeStatusQuery,
+		ClientID: clientID,
+		Metadata: metadata,
+	}
+}
+
+func (h *TransactionStatusHandler) findTarget(ctx context.Context, req *models.TransactionStatusRequest) (*models.Transaction, error) {
+	if req.TransactionID != "" {
+		return h.repo.GetByReceiptNumber(ctx, req.TransactionID)
+	}
+	return h.repo.GetByOriginatorConversationID(ctx, req.OriginatorConversationID)
+}
+
+// completeQuery marks the stored status query as answered and returns it
+func (h *TransactionStatusHandler) completeQuery(ctx context.Context, originatorConversationID string, status models.TransactionStatus, resultCode int, resultDesc string) *models.Transaction {
+	query, err := h.repo.GetByOriginatorConversationID(ctx, originatorConversationID)
+	if err != nil {
+		log.Printf("No stored status query for %s: %v", originatorConversationID, err)
+		return nil
+	}
+
+	query.SetResult(status, resultCode, resultDesc)
+	if err := h.repo.Update(ctx, query); err != nil {
+		log.Printf("Failed to update status query %s: %v", originatorConversationID, err)
+	}
+	return query
+}
+
+// queryTarget finds the transaction a status result is about, falling back to
+// the receipt number reported by M-Pesa
+func (h *TransactionStatusHandler) queryTarget(ctx context.Context, query *models.Transaction, params map[string]interface{}) *models.Transaction {
+	if query != nil {
+		if targetID, ok := query.Metadata["target_id"].(string); ok {
+			if target, err := h.repo.GetByID(ctx, targetID); err == nil {
+				return target
+			}
+		}
+	}
+
+	if receipt, ok := params["ReceiptNo"]; ok {
+		if target, err := h.repo.GetByReceiptNumber(ctx, fmt.Sprintf("%v", receipt)); err == nil {
+			return target
+		}
+	}
+	return nil
+}
+
+// updateTarget moves target to the status M-Pesa reported, from the status it
+// was read in. It returns false when the move is not allowed or target changed
+// in the meantime.
+func (h *TransactionStatusHandler) updateTarget(ctx context.Context, target *models.Transaction, status models.TransactionStatus, params map[string]interface{}) bool {
+	if !models.QueryMayMove(target.Status, status) {
+		log.Printf("Status query reported %s for transaction %s, which is %s; not updating", status, target.ID, target.Status)
+		return false
+	}
+	if target.Status != status {
+		moved, err := h.repo.UpdateStatusIf(ctx, target.ID, target.Status, status)
+		if err != nil {
+			log.Printf("Failed to update transaction %s from status query: %v", target.ID, err)
+			return false
+		}
+		if !moved {
+			log.Printf("Transaction %s changed while its status was queried; not updating", target.ID)
+			return false
+		}
+		target.Status = status
+	}
+
+	if receipt, ok := params["ReceiptNo"]; ok && target.ReceiptNumber == "" {
+		target.ReceiptNumber = fmt.Sprintf("%v", receipt)
+	}
+	if target.Metadata == nil {
+		target.Metadata = make(map[string]interface{})
+	}
+	target.Metadata["transaction_status"] = params
+
+	if err := h.repo.Update(ctx, target); err != nil {
+		log.Printf("Failed to update transaction %s from status query: %v", target.ID, err)
+	}
+
+	// A reversal whose outcome was unknown settles the transaction it reversed
+	if target.Type == models.TransactionTypeReversal {
+		originalID, _ := target.Metadata["original_id"].(string)
+		settleOriginal(ctx, h.repo, originalID, status == models.TransactionStatusSuccess)
+	}
+	return true
+}
@@ -0,0 +1,215 @@
+// ==========================
+// internal/handlers/webhook_handler.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/services"
+	"awesomeProject/internal/storage"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+type WebhookHandler struct {
+	config         *config.Config
+	webhookService *services.WebhookService
+	store          storage.WebhookStore
+}
+
+func NewWebhookHandler(cfg *config.Config, webhookService *services.WebhookService, store storage.WebhookStore) *WebhookHandler {
+	return &WebhookHandler{
+		config:         cfg,
+		webhookService: webhookService,
+		store:          store,
+	}
+}
+
+// CreateEndpoint registers a partner URL. The signing secret is only ever
+// returned here.
+func (h *WebhookHandler) CreateEndpoint(c *gin.Context) {
+	var req models.WebhookEndpointRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			ErrorCode: "INVALID_REQUEST",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	endpoint, err := h.webhookService.CreateEndpoint(c.Request.Context(), req)
+	if errors.Is(err, services.ErrUnknownEventType) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Unknown event type",
+			ErrorCode: "INVALID_EVENT_TYPE",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if errors.Is(err, services.ErrUnsafeWebhookURL) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Webhook URL not allowed",
+			ErrorCode: "INVALID_WEBHOOK_URL",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create webhook endpoint: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to create webhook endpoint",
+			ErrorCode: "WEBHOOK_CREATE_FAILED",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse{
+		Message: "Webhook endpoint created",
+		Data: map[string]interface{}{
+			"endpoint": endpoint,
+			"secret":   endpoint.Secret,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *WebhookHandler) ListEndpoints(c *gin.Context) {
+	endpoints, err := h.store.ListEndpoints(c.Request.Context())
+	if err != nil {
+		h.storeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message:   "Webhook endpoints retrieved",
+		Data:      map[string]interface{}{"endpoints": endpoints},
+		Timestamp: time.Now(),
+	})
+}
+
+// DeleteEndpoint stops new deliveries to an endpoint; queued ones are
+// dead-lettered when they next come due
+func (h *WebhookHandler) DeleteEndpoint(c *gin.Context) {
+	if err := h.store.DeleteEndpoint(c.Request.Context(), c.Param("id")); err != nil {
+		h.storeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message:   "Webhook endpoint deleted",
+		Data:      map[string]interface{}{"id": c.Param("id")},
+		Timestamp: time.Now(),
+	})
+}
+
+// ListDeliveries lists deliveries, newest first, optionally filtered by
+// endpoint_id and status
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	h.listDeliveries(c, models.WebhookDeliveryStatus(c.Query("status")))
+}
+
+// ListDeadLetters lists the deliveries that ran out of retries
+func (h *WebhookHandler) ListDeadLetters(c *gin.Context) {
+	h.listDeliveries(c, models.WebhookDeliveryDead)
+}
+
+func (h *WebhookHandler) listDeliveries(c *gin.Context, status models.WebhookDeliveryStatus) {
+	limit := defaultDeliveryLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxDeliveryLimit {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "Invalid limit",
+				ErrorCode: "INVALID_REQUEST",
+				Details:   map[string]interface{}{"limit": raw, "max": maxDeliveryLimit},
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.store.ListDeliveries(c.Request.Context(), storage.WebhookDeliveryFilter{
+		EndpointID: c.Query("endpoint_id"),
+		Status:     status,
+		Limit:      limit,
+	})
+	if err != nil {
+		h.storeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message:   "Webhook deliveries retrieved",
+		Data:      map[string]interface{}{"deliveries": deliveries},
+		Timestamp: time.Now(),
+	})
+}
+
+// GetDelivery shows a delivery with every attempt made to send it
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	delivery, err := h.store.GetDelivery(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.storeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message:   "Webhook delivery retrieved",
+		Data:      map[string]interface{}{"delivery": delivery},
+		Timestamp: time.Now(),
+	})
+}
+
+// Redeliver queues a delivery to be sent again straight away
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	delivery, err := h.webhookService.Redeliver(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.storeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse{
+		Message:   "Webhook delivery queued",
+		Data:      map[string]interface{}{"delivery": delivery},
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *WebhookHandler) storeError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:     "Not found",
+			ErrorCode: "NOT_FOUND",
+			Details:   map[string]interface{}{"id": c.Param("id")},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	log.Printf("Webhook store error: %v", err)
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:     "Failed to access webhooks",
+		ErrorCode: "WEBHOOK_STORE_FAILED",
+		Details:   map[string]interface{}{"error": err.Error()},
+		Timestamp: time.Now(),
+	})
+}
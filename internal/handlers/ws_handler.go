@@ -0,0 +1,303 @@
+// ==========================
+// internal/handlers/ws_handler.go
+// ==========================
+package handlers
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/middleware"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"awesomeProject/internal/utils"
+	ws "awesomeProject/internal/websocket"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Browsers cannot set headers on a websocket handshake, so the token may be
+// sent as the second of two subprotocols: "access_token, <token>"
+const wsTokenSubprotocol = "access_token"
+
+type WSHandler struct {
+	config   *config.Config
+	hub      *ws.Hub
+	signer   *ws.TokenSigner
+	repo     storage.TransactionRepository
+	upgrader websocket.Upgrader
+}
+
+func NewWSHandler(cfg *config.Config, hub *ws.Hub, signer *ws.TokenSigner, repo storage.TransactionRepository) *WSHandler {
+	h := &WSHandler{
+		config: cfg,
+		hub:    hub,
+		signer: signer,
+		repo:   repo,
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{wsTokenSubprotocol},
+		CheckOrigin:     h.checkOrigin,
+	}
+	return h
+}
+
+// IssueToken signs a short-lived token limiting a websocket connection to the
+// requested transactions and event types. Transactions must have been
+// initiated by the calling client; phone numbers and types span every
+// client's payments, so they need the read:events scope.
+func (h *WSHandler) IssueToken(c *gin.Context) {
+	var req models.WSTokenRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			ErrorCode: "INVALID_REQUEST",
+			Details:   map[string]interface{}{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if len(req.CheckoutRequestIDs) == 0 && len(req.OriginatorConversationIDs) == 0 &&
+		len(req.PhoneNumbers) == 0 && len(req.Types) == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "At least one of checkout_request_ids, originator_conversation_ids, phone_numbers or types is required",
+			ErrorCode: "INVALID_REQUEST",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if len(req.PhoneNumbers) > 0 || len(req.Types) > 0 {
+		if client, ok := middleware.Client(c); !ok || !client.HasScope(models.ScopeReadEvents) {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Error:     "phone_numbers and types require the read:events scope",
+				ErrorCode: "INSUFFICIENT_SCOPE",
+				Details:   map[string]interface{}{"required_scope": models.ScopeReadEvents},
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
+
+	if !h.checkOwned(c, "checkout_request_id", req.CheckoutRequestIDs, h.repo.GetByCheckoutRequestID) ||
+		!h.checkOwned(c, "originator_conversation_id", req.OriginatorConversationIDs, h.repo.GetByOriginatorConversationID) {
+		return
+	}
+
+	// Broadcasts carry numbers as 254XXXXXXXXX
+	phoneNumbers := make([]string, 0, len(req.PhoneNumbers))
+	for _, phone := range req.PhoneNumbers {
+		formatted, err := utils.FormatPhoneNumber(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     err.Error(),
+				ErrorCode: "INVALID_PHONE_NUMBER",
+				Details:   map[string]interface{}{"phone_number": phone},
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		phoneNumbers = append(phoneNumbers, formatted)
+	}
+
+	token, expiresAt, err := h.signer.Issue(ws.Claims{
+		CheckoutRequestIDs:        req.CheckoutRequestIDs,
+		OriginatorConversationIDs: req.OriginatorConversationIDs,
+		PhoneNumbers:              phoneNumbers,
+		Types:                     req.Types,
+	})
+	if err != nil {
+		log.Printf("Failed to issue websocket token: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to issue token",
+			ErrorCode: "TOKEN_ISSUE_FAILED",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Token issued",
+		Data: map[string]interface{}{
+			"token":      token,
+			"expires_at": expiresAt,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// checkOwned answers 403 and returns false unless every one of ids refers to
+// a transaction the calling client initiated. Unknown and foreign IDs get the
+// same answer, so a token request cannot probe for other clients' payments.
+func (h *WSHandler) checkOwned(c *gin.Context, field string, ids []string,
+	lookup func(ctx context.Context, id string) (*models.Transaction, error)) bool {
+	clientID := middleware.ClientID(c)
+	for _, id := range ids {
+		tx, err := lookup(c.Request.Context(), id)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Failed to look up %s %s for websocket token: %v", field, id, err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:     "Failed to look up transaction",
+				ErrorCode: "STORAGE_ERROR",
+				Timestamp: time.Now(),
+			})
+			return false
+		}
+		if err != nil || tx.ClientID != clientID {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Error:     "Transaction not found for this API client",
+				ErrorCode: "TRANSACTION_NOT_OWNED",
+				Details:   map[string]interface{}{field: id},
+				Timestamp: time.Now(),
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// Connect upgrades an authenticated request to a payments websocket
+func (h *WSHandler) Connect(c *gin.Context) {
+	claims, err := h.signer.Verify(wsToken(c.Request))
+	if err != nil {
+		errorCode := "INVALID_TOKEN"
+		if errors.Is(err, ws.ErrExpiredToken) {
+			errorCode = "TOKEN_EXPIRED"
+		}
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error:     err.Error(),
+			ErrorCode: errorCode,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Println("WebSocket upgrade error:", err)
+		return
+	}
+
+	client := ws.NewClient(h.hub, conn, claims)
+	h.hub.Register <- client
+
+	go client.WritePump()
+	go client.ReadPump()
+}
+
+// StreamEvents serves the same broadcasts as Connect as Server-Sent Events,
+// for clients behind proxies that break websockets. Topics are given as query
+// parameters and a reconnecting client is caught up from Last-Event-ID.
+func (h *WSHandler) StreamEvents(c *gin.Context) {
+	token := wsToken(c.Request)
+	if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+
+	claims, err := h.signer.Verify(token)
+	if err != nil {
+		errorCode := "INVALID_TOKEN"
+		if errors.Is(err, ws.ErrExpiredToken) {
+			errorCode = "TOKEN_EXPIRED"
+		}
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error:     err.Error(),
+			ErrorCode: errorCode,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	topics, err := ws.TopicsFromQuery(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     err.Error(),
+			ErrorCode: "INVALID_REQUEST",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	client := ws.NewStreamClient(h.hub, claims)
+	if err := client.Subscribe(topics); err != nil {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:     err.Error(),
+			ErrorCode: "SUBSCRIPTION_NOT_PERMITTED",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// EventSource resends the id of the last event it received
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	h.hub.Register <- client
+	if lastEventID != "" {
+		seq, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			log.Printf("Ignoring invalid Last-Event-ID %q", lastEventID)
+		} else {
+			client.Resume(seq)
+		}
+	}
+
+	client.StreamEvents(c.Request.Context(), c.Writer)
+}
+
+// wsToken reads the token from the token query parameter or the
+// Sec-WebSocket-Protocol header
+func wsToken(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+
+	protocols := websocket.Subprotocols(r)
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == wsTokenSubprotocol {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+// EventSchema serves the JSON Schema of the events sent by Connect and
+// StreamEvents
+func (h *WSHandler) EventSchema(c *gin.Context) {
+	c.Data(http.StatusOK, "application/schema+json", models.EventSchema)
+}
+
+// checkOrigin accepts clients that send no Origin (not a browser), origins in
+// WS_ALLOWED_ORIGINS, or, when no allowlist is configured, the same host
+func (h *WSHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if len(h.config.WSAllowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+
+	for _, allowed := range h.config.WSAllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	log.Printf("Rejected websocket connection from origin %s", origin)
+	return false
+}
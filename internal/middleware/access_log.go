@@ -0,0 +1,65 @@
+// ==========================
+// internal/middleware/access_log.go
+// ==========================
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Query parameters that carry credentials; browsers cannot set headers on
+// websocket handshakes or EventSource requests
+var secretQueryParams = []string{"token"}
+
+// AccessLog is gin's request logger with the values of secretQueryParams
+// replaced, so tokens never reach the access log
+func AccessLog() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
+		}
+
+		// Same layout as gin's default formatter
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			methodColor, param.Method, resetColor,
+			redactQuery(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// redactQuery replaces the secret parameters in the query string of path
+func redactQuery(path string) string {
+	base, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Cannot tell the parameters apart; drop the query altogether
+		return base + "?REDACTED"
+	}
+	redacted := false
+	for _, name := range secretQueryParams {
+		if _, ok := query[name]; ok {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return base + "?" + query.Encode()
+}
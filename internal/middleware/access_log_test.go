@@ -0,0 +1,22 @@
+// ==========================
+// internal/middleware/access_log_test.go
+// ==========================
+package middleware
+
+import "testing"
+
+func TestRedactQueryHidesToken(t *testing.T) {
+	got := redactQuery("/ws?token=abc.def&topic=type:b2c_callback")
+
+	if got != "/ws?token=REDACTED&topic=type%3Ab2c_callback" {
+		t.Errorf("redactQuery() = %q", got)
+	}
+}
+
+func TestRedactQueryKeepsOtherPaths(t *testing.T) {
+	for _, path := range []string{"/api/v1/b2c/payment", "/ws?topic=type:b2c_callback"} {
+		if got := redactQuery(path); got != path {
+			t.Errorf("redactQuery(%q) = %q, want it unchanged", path, got)
+		}
+	}
+}
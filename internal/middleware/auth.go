@@ -0,0 +1,106 @@
+// ==========================
+// internal/middleware/auth.go
+// ==========================
+package middleware
+
+import (
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+	apiClientKey = "api_client"
+)
+
+// APIKeyAuth authenticates REST calls by the key in X-API-Key (or an
+// "Authorization: Bearer" header) and checks the scope each route needs
+type APIKeyAuth struct {
+	clients *services.APIClientService
+}
+
+func NewAPIKeyAuth(clients *services.APIClientService) *APIKeyAuth {
+	return &APIKeyAuth{clients: clients}
+}
+
+// Require lets the request through only for an active client granted scope
+func (a *APIKeyAuth) Require(scope models.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(APIKeyHeader)
+		if key == "" {
+			if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+				key = strings.TrimSpace(bearer)
+			}
+		}
+		if key == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error:     "API key required",
+				ErrorCode: "UNAUTHORIZED",
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		client, err := a.clients.Authenticate(c.Request.Context(), key)
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			log.Printf("Rejected invalid API key on %s from %s", c.Request.URL.Path, c.ClientIP())
+			c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error:     "Invalid API key",
+				ErrorCode: "UNAUTHORIZED",
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to authenticate API key: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:     "Failed to authenticate request",
+				ErrorCode: "INTERNAL_ERROR",
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		if !client.HasScope(scope) {
+			log.Printf("API client %s (%s) lacks scope %s for %s", client.ID, client.Name, scope, c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+				Error:     "API key lacks the required scope",
+				ErrorCode: "INSUFFICIENT_SCOPE",
+				Details:   map[string]interface{}{"required_scope": scope},
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		c.Set(apiClientKey, client)
+		c.Next()
+	}
+}
+
+// Client returns the API client that made the request, if it came through
+// APIKeyAuth
+func Client(c *gin.Context) (*models.APIClient, bool) {
+	value, ok := c.Get(apiClientKey)
+	if !ok {
+		return nil, false
+	}
+	client, ok := value.(*models.APIClient)
+	return client, ok
+}
+
+// ClientID returns the ID of the calling API client, or "" if there is none
+func ClientID(c *gin.Context) string {
+	if client, ok := Client(c); ok {
+		return client.ID
+	}
+	return ""
+}
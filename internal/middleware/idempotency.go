@@ -0,0 +1,167 @@
+// ==========================
+// internal/middleware/idempotency.go
+// ==========================
+package middleware
+
+import (
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+
+	// Set by ReleaseIdempotencyKey
+	idempotencyReleaseKey = "idempotency_release"
+)
+
+// ReleaseIdempotencyKey tells Idempotency that the request failed before
+// anything was sent to Daraja, so its key is freed for a retry instead of
+// replaying this response. Handlers must not call it once a request may have
+// reached Daraja: a retry with the same key would then pay twice.
+func ReleaseIdempotencyKey(c *gin.Context) {
+	c.Set(idempotencyReleaseKey, true)
+}
+
+// responseRecorder keeps a copy of everything the handler writes
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response for requests repeating an
+// Idempotency-Key with the same body, and rejects a reused key whose body
+// differs. Requests without the header are passed through untouched. Keys
+// are kept per API client, so clients cannot see each other's responses.
+// Every response is kept, failures included, unless the handler called
+// ReleaseIdempotencyKey; a request that panics is replayed as a 500 because
+// its payment may have gone out.
+func Idempotency(store storage.IdempotencyStore, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		scope := scope
+		if clientID := ClientID(c); clientID != "" {
+			scope = clientID + ":" + scope
+		}
+
+		if len(key) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "Idempotency-Key is too long",
+				ErrorCode: "INVALID_IDEMPOTENCY_KEY",
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "Failed to read request body",
+				ErrorCode: "INVALID_REQUEST",
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		sum := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(sum[:])
+
+		record, reserved, err := store.Reserve(c.Request.Context(), scope, key, requestHash)
+		if err != nil {
+			log.Printf("Idempotency store error for key %s: %v", key, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:     "Failed to check idempotency key",
+				ErrorCode: "IDEMPOTENCY_STORE_ERROR",
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		if !reserved {
+			switch {
+			case record.RequestHash != requestHash:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+					Error:     "Idempotency-Key was already used with a different request body",
+					ErrorCode: "IDEMPOTENCY_KEY_REUSED",
+					Details:   map[string]interface{}{"idempotency_key": key},
+					Timestamp: time.Now(),
+				})
+			case !record.Completed:
+				c.AbortWithStatusJSON(http.StatusConflict, models.ErrorResponse{
+					Error:     "A request with this Idempotency-Key is still being processed",
+					ErrorCode: "IDEMPOTENCY_REQUEST_IN_PROGRESS",
+					Details:   map[string]interface{}{"idempotency_key": key},
+					Timestamp: time.Now(),
+				})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.StatusCode, "application/json; charset=utf-8", record.ResponseBody)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		defer func() {
+			if r := recover(); r != nil {
+				body, _ := json.Marshal(models.ErrorResponse{
+					Error:     "Request failed; the payment may have been sent",
+					ErrorCode: "OUTCOME_UNKNOWN",
+					Timestamp: time.Now(),
+				})
+				finishIdempotencyKey(c, store, scope, key, http.StatusInternalServerError, body)
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		finishIdempotencyKey(c, store, scope, key, recorder.Status(), recorder.body.Bytes())
+	}
+}
+
+// finishIdempotencyKey frees key if the handler released it and otherwise
+// stores the response to replay
+func finishIdempotencyKey(c *gin.Context, store storage.IdempotencyStore, scope, key string, status int, body []byte) {
+	// Use a fresh context: the client may have gone away, but the payment went out
+	ctx := context.Background()
+	if c.GetBool(idempotencyReleaseKey) {
+		if err := store.Release(ctx, scope, key); err != nil {
+			log.Printf("Failed to release idempotency key %s: %v", key, err)
+		}
+		return
+	}
+	if err := store.Complete(ctx, scope, key, status, body); err != nil {
+		log.Printf("Failed to store response for idempotency key %s: %v", key, err)
+	}
+}
@@ -0,0 +1,160 @@
+// ==========================
+// internal/middleware/idempotency_test.go
+// ==========================
+package middleware
+
+import (
+	"awesomeProject/internal/storage"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// idempotentRouter serves handler on POST /pay behind Idempotency
+func idempotentRouter(handler gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	r.POST("/pay", Idempotency(storage.NewMemoryIdempotencyStore(), "test"), handler)
+	return r
+}
+
+func postPayment(r http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/pay", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func errorCode(w *httptest.ResponseRecorder) string {
+	var body struct {
+		ErrorCode string `json:"error_code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return body.ErrorCode
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	calls := 0
+	r := idempotentRouter(func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"call": calls})
+	})
+
+	first := postPayment(r, "key-1", `{"amount":100}`)
+	second := postPayment(r, "key-1", `{"amount":100}`)
+
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+	if second.Code != http.StatusOK || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("replay is missing the Idempotent-Replayed header")
+	}
+}
+
+func TestIdempotencyRejectsReusedKey(t *testing.T) {
+	r := idempotentRouter(func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	postPayment(r, "key-1", `{"amount":100}`)
+	w := postPayment(r, "key-1", `{"amount":200}`)
+
+	if w.Code != http.StatusUnprocessableEntity || errorCode(w) != "IDEMPOTENCY_KEY_REUSED" {
+		t.Errorf("reused key = %d %s, want %d IDEMPOTENCY_KEY_REUSED", w.Code, errorCode(w), http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotencyRejectsKeyInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	r := idempotentRouter(func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- postPayment(r, "key-1", `{"amount":100}`)
+	}()
+	<-started
+
+	w := postPayment(r, "key-1", `{"amount":100}`)
+	close(release)
+	<-done
+
+	if w.Code != http.StatusConflict || errorCode(w) != "IDEMPOTENCY_REQUEST_IN_PROGRESS" {
+		t.Errorf("key in progress = %d %s, want %d IDEMPOTENCY_REQUEST_IN_PROGRESS", w.Code, errorCode(w), http.StatusConflict)
+	}
+}
+
+func TestIdempotencyKeepsKeyAfterServerError(t *testing.T) {
+	calls := 0
+	r := idempotentRouter(func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusInternalServerError, gin.H{"error_code": "PAYMENT_FAILED"})
+	})
+
+	postPayment(r, "key-1", `{"amount":100}`)
+	w := postPayment(r, "key-1", `{"amount":100}`)
+
+	// Daraja may have received the first request; a retry must not pay again
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+	if w.Code != http.StatusInternalServerError || errorCode(w) != "PAYMENT_FAILED" {
+		t.Errorf("retry = %d %s, want the stored %d PAYMENT_FAILED", w.Code, errorCode(w), http.StatusInternalServerError)
+	}
+}
+
+func TestIdempotencyFreesReleasedKey(t *testing.T) {
+	calls := 0
+	r := idempotentRouter(func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			ReleaseIdempotencyKey(c)
+			c.JSON(http.StatusInternalServerError, gin.H{"error_code": "STORAGE_ERROR"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	postPayment(r, "key-1", `{"amount":100}`)
+	w := postPayment(r, "key-1", `{"amount":100}`)
+
+	if calls != 2 || w.Code != http.StatusOK {
+		t.Errorf("retry = %d after %d calls, want %d after 2", w.Code, calls, http.StatusOK)
+	}
+}
+
+func TestIdempotencyKeepsKeyAfterPanic(t *testing.T) {
+	calls := 0
+	r := idempotentRouter(func(c *gin.Context) {
+		calls++
+		panic("payment sent, then something broke")
+	})
+
+	postPayment(r, "key-1", `{"amount":100}`)
+	w := postPayment(r, "key-1", `{"amount":100}`)
+
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+	if w.Code != http.StatusInternalServerError || errorCode(w) != "OUTCOME_UNKNOWN" {
+		t.Errorf("retry = %d %s, want %d OUTCOME_UNKNOWN", w.Code, errorCode(w), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,75 @@
+// ==========================
+// internal/models/api_client.go
+// ==========================
+package models
+
+import "time"
+
+// Scope is a permission granted to an API client
+type Scope string
+
+const (
+	ScopeSTKInitiate      Scope = "stk:initiate"
+	ScopeB2CPay           Scope = "b2c:pay"
+	ScopeB2CApprove       Scope = "b2c:approve" // Decide on payouts held for approval
+	ScopeB2BPay           Scope = "b2b:pay"
+	ScopeReversalInitiate Scope = "reversal:initiate"
+	ScopeReadTransactions Scope = "read:transactions" // Status queries and event subscriptions
+	ScopeReadEvents       Scope = "read:events"       // Subscribe by phone number or type, whoever initiated the payment
+	ScopeReadBalance      Scope = "read:balance"
+	ScopeWebhooksManage   Scope = "webhooks:manage"
+	ScopeClientsManage    Scope = "clients:manage"
+)
+
+// Scopes lists every scope a client can be granted
+var Scopes = []Scope{
+	ScopeSTKInitiate,
+	ScopeB2CPay,
+	ScopeB2CApprove,
+	ScopeB2BPay,
+	ScopeReversalInitiate,
+	ScopeReadTransactions,
+	ScopeReadEvents,
+	ScopeReadBalance,
+	ScopeWebhooksManage,
+	ScopeClientsManage,
+}
+
+// Valid reports whether s is one of Scopes
+func (s Scope) Valid() bool {
+	for _, scope := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIClient is a system allowed to call the REST API with its own key.
+// Only a hash of the key is stored.
+type APIClient struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	KeyPrefix string     `json:"key_prefix"` // Start of the key, to tell keys apart
+	KeyHash   string     `json:"-"`
+	Scopes    []Scope    `json:"scopes"`
+	CreatedBy string     `json:"created_by,omitempty"` // Client that created this one; empty from the CLI
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the client was granted scope
+func (c *APIClient) HasScope(scope Scope) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Client request model (snake_case for JSON)
+type APIClientRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+}
@@ -0,0 +1,26 @@
+// ==========================
+// internal/models/audit.go
+// ==========================
+package models
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditB2CApprovalRequested AuditAction = "b2c.approval_requested"
+	AuditB2CApproved          AuditAction = "b2c.approved"
+	AuditB2CRejected          AuditAction = "b2c.rejected"
+	AuditB2CApprovalExpired   AuditAction = "b2c.approval_expired"
+)
+
+// AuditEntry records who did what to which record. Entries are never
+// changed once written.
+type AuditEntry struct {
+	ID        string                 `json:"id"`
+	Action    AuditAction            `json:"action"`
+	ActorID   string                 `json:"actor_id"`   // API client, or "system"
+	SubjectID string                 `json:"subject_id"` // Transaction.ID
+	Details   map[string]interface{} `json:"details,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
@@ -0,0 +1,49 @@
+// ==========================
+// internal/models/b2b.go
+// ==========================
+package models
+
+// Client request model (snake_case for JSON)
+type B2BPaymentRequest struct {
+	CommandID                string `json:"command_id" binding:"required,oneof=BusinessPayBill BusinessBuyGoods"`
+	Amount                   int    `json:"amount" binding:"required,gt=0"`
+	ReceiverShortCode        string `json:"receiver_short_code" binding:"required,numeric"`
+	AccountReference         string `json:"account_reference" binding:"max=13"` // Required for BusinessPayBill
+	Requester                string `json:"requester,omitempty"`                // Customer phone number, optional
+	Remarks                  string `json:"remarks" binding:"max=100"`
+	OriginatorConversationID string `json:"originator_conversation_id,omitempty"`
+}
+
+type B2BPaymentResponse struct {
+	ConversationID           string `json:"conversation_id"`
+	OriginatorConversationID string `json:"originator_conversation_id"`
+	ResponseCode             string `json:"response_code"`
+	ResponseDescription      string `json:"response_description"`
+}
+
+// M-Pesa callback models (PascalCase to match M-Pesa's response)
+type B2BCallback struct {
+	ResultType               int                  `json:"ResultType"`
+	ResultCode               int                  `json:"ResultCode"`
+	ResultDesc               string               `json:"ResultDesc"`
+	OriginatorConversationID string               `json:"OriginatorConversationID"`
+	ConversationID           string               `json:"ConversationID"`
+	TransactionID            string               `json:"TransactionID"`
+	ResultParameters         *B2CResultParameters `json:"ResultParameters,omitempty"`
+	ReferenceData            interface{}          `json:"ReferenceData,omitempty"` // Shape varies between B2B results
+}
+
+type B2BResultRequest struct {
+	Result B2BCallback `json:"Result"`
+}
+
+// Helper method to extract result parameters as map
+func (cb *B2BCallback) GetResultParametersMap() map[string]interface{} {
+	result := make(map[string]interface{})
+	if cb.ResultParameters != nil {
+		for _, param := range cb.ResultParameters.ResultParameter {
+			result[param.Key] = param.Value
+		}
+	}
+	return result
+}
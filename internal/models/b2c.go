@@ -20,6 +20,27 @@ type B2CPaymentResponse struct {
 	ResponseDescription      string `json:"response_description"`
 }
 
+// ApprovalReason is why a payout was held for approval
+type ApprovalReason string
+
+const (
+	ApprovalReasonAmount       ApprovalReason = "amount_above_threshold"
+	ApprovalReasonNewRecipient ApprovalReason = "new_recipient"
+)
+
+type ApprovalDecision string
+
+const (
+	ApprovalDecisionApprove ApprovalDecision = "approve"
+	ApprovalDecisionReject  ApprovalDecision = "reject"
+)
+
+// Approval decision request model (snake_case for JSON)
+type B2CApprovalDecisionRequest struct {
+	Decision ApprovalDecision `json:"decision" binding:"required,oneof=approve reject"`
+	Note     string           `json:"note,omitempty" binding:"max=200"`
+}
+
 // M-Pesa callback models (PascalCase to match M-Pesa's response)
 type B2CResultParameter struct {
 	Key   string      `json:"Key"`
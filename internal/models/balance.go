@@ -0,0 +1,108 @@
+// ==========================
+// internal/models/balance.go
+// ==========================
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AccountBalance struct {
+	Name      string  `json:"name"`
+	Currency  string  `json:"currency"`
+	Current   float64 `json:"current"`
+	Available float64 `json:"available"`
+	Reserved  float64 `json:"reserved"`
+	Uncleared float64 `json:"uncleared"`
+}
+
+type BalanceSnapshot struct {
+	Accounts    []AccountBalance `json:"accounts"`
+	CompletedAt string           `json:"completed_at,omitempty"` // BOCompletedTime reported by M-Pesa
+	RetrievedAt time.Time        `json:"retrieved_at"`
+}
+
+// Account returns the balance of the named account, e.g. "Utility Account"
+func (s *BalanceSnapshot) Account(name string) (AccountBalance, bool) {
+	for _, account := range s.Accounts {
+		if strings.EqualFold(account.Name, name) {
+			return account, true
+		}
+	}
+	return AccountBalance{}, false
+}
+
+// ParseAccountBalances parses M-Pesa's AccountBalance result parameter, which
+// looks like "Working Account|KES|700000.00|700000.00|0.00|0.00&Utility Account|KES|..."
+// with fields name|currency|current|available|reserved|uncleared
+func ParseAccountBalances(raw string) ([]AccountBalance, error) {
+	accounts := make([]AccountBalance, 0)
+
+	for _, entry := range strings.Split(raw, "&") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, "|")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("unexpected account balance entry %q", entry)
+		}
+
+		amounts := make([]float64, 4)
+		for i, field := range fields[2:] {
+			amount, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid amount %q in account balance entry %q", field, entry)
+			}
+			amounts[i] = amount
+		}
+
+		accounts = append(accounts, AccountBalance{
+			Name:      strings.TrimSpace(fields[0]),
+			Currency:  strings.TrimSpace(fields[1]),
+			Current:   amounts[0],
+			Available: amounts[1],
+			Reserved:  amounts[2],
+			Uncleared: amounts[3],
+		})
+	}
+
+	return accounts, nil
+}
+
+type AccountBalanceResponse struct {
+	ConversationID           string `json:"conversation_id"`
+	OriginatorConversationID string `json:"originator_conversation_id"`
+	ResponseCode             string `json:"response_code"`
+	ResponseDescription      string `json:"response_description"`
+}
+
+// M-Pesa callback models (PascalCase to match M-Pesa's response)
+type AccountBalanceCallback struct {
+	ResultType               int                  `json:"ResultType"`
+	ResultCode               int                  `json:"ResultCode"`
+	ResultDesc               string               `json:"ResultDesc"`
+	OriginatorConversationID string               `json:"OriginatorConversationID"`
+	ConversationID           string               `json:"ConversationID"`
+	TransactionID            string               `json:"TransactionID"`
+	ResultParameters         *B2CResultParameters `json:"ResultParameters,omitempty"`
+	ReferenceData            interface{}          `json:"ReferenceData,omitempty"`
+}
+
+type AccountBalanceResultRequest struct {
+	Result AccountBalanceCallback `json:"Result"`
+}
+
+// Helper method to extract result parameters as map
+func (cb *AccountBalanceCallback) GetResultParametersMap() map[string]interface{} {
+	result := make(map[string]interface{})
+	if cb.ResultParameters != nil {
+		for _, param := range cb.ResultParameters.ResultParameter {
+			result[param.Key] = param.Value
+		}
+	}
+	return result
+}
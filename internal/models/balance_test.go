@@ -0,0 +1,36 @@
+// ==========================
+// internal/models/balance_test.go
+// ==========================
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAccountBalances(t *testing.T) {
+	accounts, err := ParseAccountBalances(
+		"Working Account|KES|700000.00|700000.00|0.00|0.00&Utility Account| KES |228037.00|228000.50|37.00|0.50&")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AccountBalance{
+		{Name: "Working Account", Currency: "KES", Current: 700000, Available: 700000},
+		{Name: "Utility Account", Currency: "KES", Current: 228037, Available: 228000.5, Reserved: 37, Uncleared: 0.5},
+	}
+	if !reflect.DeepEqual(accounts, want) {
+		t.Errorf("ParseAccountBalances() = %+v, want %+v", accounts, want)
+	}
+}
+
+func TestParseAccountBalancesRejectsMalformedEntries(t *testing.T) {
+	for _, raw := range []string{
+		"Working Account|KES|700000.00|700000.00|0.00",
+		"Working Account|KES|seven|700000.00|0.00|0.00",
+	} {
+		if accounts, err := ParseAccountBalances(raw); err == nil {
+			t.Errorf("ParseAccountBalances(%q) = %+v, want an error", raw, accounts)
+		}
+	}
+}
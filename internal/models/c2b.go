@@ -0,0 +1,44 @@
+// ==========================
+// internal/models/c2b.go
+// ==========================
+package models
+
+// M-Pesa C2B models (PascalCase to match M-Pesa's payloads)
+type C2BRegisterURLResponse struct {
+	OriginatorConversationID string `json:"OriginatorCoversationID"` // Typo in M-Pesa API
+	ResponseCode             string `json:"ResponseCode"`
+	ResponseDescription      string `json:"ResponseDescription"`
+}
+
+// C2BPayment is posted by M-Pesa to both the validation and confirmation URLs
+type C2BPayment struct {
+	TransactionType   string `json:"TransactionType"`
+	TransID           string `json:"TransID"`
+	TransTime         string `json:"TransTime"`
+	TransAmount       string `json:"TransAmount"`
+	BusinessShortCode string `json:"BusinessShortCode"`
+	BillRefNumber     string `json:"BillRefNumber"`
+	InvoiceNumber     string `json:"InvoiceNumber"`
+	OrgAccountBalance string `json:"OrgAccountBalance"`
+	ThirdPartyTransID string `json:"ThirdPartyTransID"`
+	MSISDN            string `json:"MSISDN"`
+	FirstName         string `json:"FirstName"`
+	MiddleName        string `json:"MiddleName"`
+	LastName          string `json:"LastName"`
+}
+
+// C2B validation result codes understood by M-Pesa
+const (
+	C2BResultAccepted       = "0"
+	C2BResultInvalidMSISDN  = "C2B00011"
+	C2BResultInvalidAccount = "C2B00012"
+	C2BResultInvalidAmount  = "C2B00013"
+	C2BResultInvalidKYC     = "C2B00014"
+	C2BResultInvalidCode    = "C2B00015"
+	C2BResultOtherError     = "C2B00016"
+)
+
+type C2BValidationResponse struct {
+	ResultCode string `json:"ResultCode"`
+	ResultDesc string `json:"ResultDesc"`
+}
@@ -0,0 +1,194 @@
+// ==========================
+// internal/models/events.go
+// ==========================
+package models
+
+import (
+	_ "embed"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventVersion is bumped whenever an event payload changes incompatibly.
+// schema/events.v1.json describes version 1.
+const EventVersion = 1
+
+// EventSchema is the JSON Schema for every event broadcast to clients
+//
+//go:embed schema/events.v1.json
+var EventSchema []byte
+
+type EventType string
+
+const (
+	EventSTKCallback              EventType = "stk_callback"
+	EventB2CInitiated             EventType = "b2c_initiated"
+	EventB2CCallback              EventType = "b2c_callback"
+	EventB2CTimeout               EventType = "b2c_timeout"
+	EventB2CApprovalRequired      EventType = "b2c_approval_required"
+	EventB2CApproved              EventType = "b2c_approved"
+	EventB2CRejected              EventType = "b2c_rejected"
+	EventB2BInitiated             EventType = "b2b_initiated"
+	EventB2BCallback              EventType = "b2b_callback"
+	EventB2BTimeout               EventType = "b2b_timeout"
+	EventC2BConfirmation          EventType = "c2b_confirmation"
+	EventTransactionStatusResult  EventType = "transaction_status_result"
+	EventTransactionStatusTimeout EventType = "transaction_status_timeout"
+	EventReversalInitiated        EventType = "reversal_initiated"
+	EventReversalResult           EventType = "reversal_result"
+	EventReversalTimeout          EventType = "reversal_timeout"
+
+	// A callback that failed cross-checking, sent instead of its usual event
+	EventSecurityAlert EventType = "security_alert"
+)
+
+var eventTypes = map[EventType]bool{
+	EventSTKCallback:              true,
+	EventB2CInitiated:             true,
+	EventB2CCallback:              true,
+	EventB2CTimeout:               true,
+	EventB2CApprovalRequired:      true,
+	EventB2CApproved:              true,
+	EventB2CRejected:              true,
+	EventB2BInitiated:             true,
+	EventB2BCallback:              true,
+	EventB2BTimeout:               true,
+	EventC2BConfirmation:          true,
+	EventTransactionStatusResult:  true,
+	EventTransactionStatusTimeout: true,
+	EventReversalInitiated:        true,
+	EventReversalResult:           true,
+	EventReversalTimeout:          true,
+	EventSecurityAlert:            true,
+}
+
+// Valid reports whether t is one of the event types above
+func (t EventType) Valid() bool {
+	return eventTypes[t]
+}
+
+// Event is the envelope every broadcast is sent in. Payload holds the
+// type-specific fields; the ids needed to match an event to a transaction are
+// always in Correlation.
+type Event struct {
+	ID          string           `json:"id"`
+	Seq         uint64           `json:"seq,omitempty"` // Assigned when broadcast
+	Type        EventType        `json:"type"`
+	Version     int              `json:"version"`
+	OccurredAt  time.Time        `json:"occurred_at"`
+	Correlation EventCorrelation `json:"correlation"`
+	Payload     interface{}      `json:"payload"`
+}
+
+type EventCorrelation struct {
+	TransactionRef           string `json:"transaction_ref,omitempty"` // Transaction.ID
+	CheckoutRequestID        string `json:"checkout_request_id,omitempty"`
+	MerchantRequestID        string `json:"merchant_request_id,omitempty"`
+	ConversationID           string `json:"conversation_id,omitempty"`
+	OriginatorConversationID string `json:"originator_conversation_id,omitempty"`
+	TransactionID            string `json:"transaction_id,omitempty"` // M-Pesa receipt number
+	PhoneNumber              string `json:"phone_number,omitempty"`
+}
+
+func NewEvent(eventType EventType, correlation EventCorrelation, payload interface{}) Event {
+	return Event{
+		ID:          uuid.New().String(),
+		Type:        eventType,
+		Version:     EventVersion,
+		OccurredAt:  time.Now().UTC(),
+		Correlation: correlation,
+		Payload:     payload,
+	}
+}
+
+// STKResultPayload is the payload of stk_callback
+type STKResultPayload struct {
+	Status     STKStatus              `json:"status"`
+	ResultCode int                    `json:"result_code"`
+	ResultDesc string                 `json:"result_desc"`
+	Amount     int                    `json:"amount,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"` // CallbackMetadata items by name
+}
+
+// PaymentInitiatedPayload is the payload of b2c_initiated and b2b_initiated
+type PaymentInitiatedPayload struct {
+	Amount            int    `json:"amount"`
+	CommandID         string `json:"command_id"`
+	ReceiverShortCode string `json:"receiver_short_code,omitempty"` // B2B only
+	AccountReference  string `json:"account_reference,omitempty"`   // B2B only
+}
+
+// PaymentResultPayload is the payload of b2c_callback, b2c_timeout,
+// b2b_callback and b2b_timeout
+type PaymentResultPayload struct {
+	Status           TransactionStatus      `json:"status"`
+	ResultCode       int                    `json:"result_code"`
+	ResultDesc       string                 `json:"result_desc"`
+	ResultParameters map[string]interface{} `json:"result_parameters,omitempty"`
+}
+
+// ApprovalPayload is the payload of b2c_approval_required, b2c_approved and
+// b2c_rejected
+type ApprovalPayload struct {
+	Amount      int              `json:"amount"`
+	CommandID   string           `json:"command_id"`
+	Reasons     []ApprovalReason `json:"reasons,omitempty"`
+	RequestedBy string           `json:"requested_by"`         // API client that initiated the payout
+	DecidedBy   string           `json:"decided_by,omitempty"` // API client, or "system" on expiry
+	Note        string           `json:"note,omitempty"`
+}
+
+// C2BConfirmationPayload is the payload of c2b_confirmation
+type C2BConfirmationPayload struct {
+	TransactionType  string `json:"transaction_type"`
+	Amount           string `json:"amount"`
+	AccountReference string `json:"account_reference"`
+	FirstName        string `json:"first_name,omitempty"`
+	TransTime        string `json:"trans_time"`
+}
+
+// TransactionStatusPayload is the payload of transaction_status_result and
+// transaction_status_timeout. Status is set when the queried transaction was
+// found and updated.
+type TransactionStatusPayload struct {
+	ResultCode        int                    `json:"result_code"`
+	ResultDesc        string                 `json:"result_desc"`
+	TransactionStatus string                 `json:"transaction_status,omitempty"` // As reported by M-Pesa
+	Status            TransactionStatus      `json:"status,omitempty"`
+	ResultParameters  map[string]interface{} `json:"result_parameters,omitempty"`
+}
+
+// ReversalPayload is the payload of reversal_initiated, reversal_result and
+// reversal_timeout
+type ReversalPayload struct {
+	Status                TransactionStatus      `json:"status"`
+	Amount                int                    `json:"amount,omitempty"`
+	OriginalTransactionID string                 `json:"original_transaction_id,omitempty"` // Receipt being reversed
+	ResultCode            int                    `json:"result_code,omitempty"`
+	ResultDesc            string                 `json:"result_desc,omitempty"`
+	ResultParameters      map[string]interface{} `json:"result_parameters,omitempty"`
+}
+
+type SecurityAlertReason string
+
+const (
+	SecurityAlertUnknownTransaction SecurityAlertReason = "unknown_transaction"
+	SecurityAlertCallbackMismatch   SecurityAlertReason = "callback_mismatch"
+)
+
+// SecurityAlertPayload is the payload of security_alert
+type SecurityAlertPayload struct {
+	Reason     SecurityAlertReason `json:"reason"`
+	Source     EventType           `json:"source"` // The event the callback would have produced
+	ResultCode int                 `json:"result_code"`
+	ResultDesc string              `json:"result_desc,omitempty"`
+	Mismatches []CallbackMismatch  `json:"mismatches,omitempty"`
+}
+
+// CallbackMismatch is a callback field that disagrees with what we requested
+type CallbackMismatch struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Received string `json:"received"`
+}
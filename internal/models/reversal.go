@@ -0,0 +1,45 @@
+// ==========================
+// internal/models/reversal.go
+// ==========================
+package models
+
+// Client request model (snake_case for JSON)
+type ReversalRequest struct {
+	TransactionID string `json:"transaction_id" binding:"required"` // M-Pesa receipt of the payment to reverse
+	Remarks       string `json:"remarks" binding:"max=100"`
+	Occasion      string `json:"occasion,omitempty" binding:"max=100"`
+}
+
+type ReversalResponse struct {
+	ConversationID           string `json:"conversation_id"`
+	OriginatorConversationID string `json:"originator_conversation_id"`
+	ResponseCode             string `json:"response_code"`
+	ResponseDescription      string `json:"response_description"`
+}
+
+// M-Pesa callback models (PascalCase to match M-Pesa's response)
+type ReversalCallback struct {
+	ResultType               int                  `json:"ResultType"`
+	ResultCode               int                  `json:"ResultCode"`
+	ResultDesc               string               `json:"ResultDesc"`
+	OriginatorConversationID string               `json:"OriginatorConversationID"`
+	ConversationID           string               `json:"ConversationID"`
+	TransactionID            string               `json:"TransactionID"`
+	ResultParameters         *B2CResultParameters `json:"ResultParameters,omitempty"`
+	ReferenceData            interface{}          `json:"ReferenceData,omitempty"`
+}
+
+type ReversalResultRequest struct {
+	Result ReversalCallback `json:"Result"`
+}
+
+// Helper method to extract result parameters as map
+func (cb *ReversalCallback) GetResultParametersMap() map[string]interface{} {
+	result := make(map[string]interface{})
+	if cb.ResultParameters != nil {
+		for _, param := range cb.ResultParameters.ResultParameter {
+			result[param.Key] = param.Value
+		}
+	}
+	return result
+}
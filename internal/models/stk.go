@@ -63,3 +63,64 @@ type ErrorResponse struct {
 	Details   map[string]interface{} `json:"details,omitempty"`
 	Timestamp time.Time              `json:"timestamp"`
 }
+
+// STKStatus is the resolved state of an STK Push transaction
+type STKStatus string
+
+const (
+	STKStatusPending   STKStatus = "PENDING"
+	STKStatusSuccess   STKStatus = "SUCCESS"
+	STKStatusCancelled STKStatus = "CANCELLED"
+	STKStatusTimeout   STKStatus = "TIMEOUT"
+	STKStatusFailed    STKStatus = "FAILED"
+)
+
+// IsFinal reports whether no further state change is expected
+func (s STKStatus) IsFinal() bool {
+	return s != STKStatusPending
+}
+
+// STKStatusFromResultCode maps Daraja's STK ResultCode values to a typed status
+func STKStatusFromResultCode(code int) STKStatus {
+	switch code {
+	case 0:
+		return STKStatusSuccess
+	case 1032: // Request cancelled by user
+		return STKStatusCancelled
+	case 1037, 1019: // DS timeout, user cannot be reached / transaction expired
+		return STKStatusTimeout
+	case 4999: // Transaction still under processing
+		return STKStatusPending
+	default:
+		return STKStatusFailed
+	}
+}
+
+type STKStatusResult struct {
+	MerchantRequestID string    `json:"merchant_request_id"`
+	CheckoutRequestID string    `json:"checkout_request_id"`
+	Status            STKStatus `json:"status"`
+	ResultCode        int       `json:"result_code"`
+	ResultDesc        string    `json:"result_desc"`
+}
+
+// ToCallback converts a resolved query result into the shape Safaricom posts to the callback URL
+func (r *STKStatusResult) ToCallback() STKCallback {
+	return STKCallback{
+		MerchantRequestID: r.MerchantRequestID,
+		CheckoutRequestID: r.CheckoutRequestID,
+		ResultCode:        r.ResultCode,
+		ResultDesc:        r.ResultDesc,
+	}
+}
+
+// Helper method to extract callback metadata items as map
+func (cb *STKCallback) GetMetadataMap() map[string]interface{} {
+	result := make(map[string]interface{})
+	if cb.CallbackMetadata != nil {
+		for _, item := range cb.CallbackMetadata.Item {
+			result[item.Name] = item.Value
+		}
+	}
+	return result
+}
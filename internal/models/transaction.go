@@ -0,0 +1,67 @@
+// ==========================
+// internal/models/transaction.go
+// ==========================
+package models
+
+import "time"
+
+type TransactionType string
+
+const (
+	TransactionTypeSTK      TransactionType = "stk"
+	TransactionTypeB2C      TransactionType = "b2c"
+	TransactionTypeB2B      TransactionType = "b2b"
+	TransactionTypeC2B      TransactionType = "c2b"
+	TransactionTypeReversal TransactionType = "reversal"
+
+	// Queries we send to Daraja, stored so their async results can be correlated
+	TransactionTypeStatusQuery TransactionType = "status_query"
+)
+
+type TransactionStatus string
+
+const (
+	TransactionStatusPending   TransactionStatus = "PENDING"
+	TransactionStatusSuccess   TransactionStatus = "SUCCESS"
+	TransactionStatusFailed    TransactionStatus = "FAILED"
+	TransactionStatusCancelled TransactionStatus = "CANCELLED"
+	TransactionStatusTimeout   TransactionStatus = "TIMEOUT"
+
+	// A successful incoming payment moves to REVERSING and then REVERSED
+	TransactionStatusReversing TransactionStatus = "REVERSING"
+	TransactionStatusReversed  TransactionStatus = "REVERSED"
+
+	// A B2C payout held for approval moves to PENDING once approved and sent,
+	// to REJECTED, or to CANCELLED if nobody decides in time
+	TransactionStatusAwaitingApproval TransactionStatus = "AWAITING_APPROVAL"
+	TransactionStatusRejected         TransactionStatus = "REJECTED"
+)
+
+// Transaction is the persisted record of a payment we initiated or received
+type Transaction struct {
+	ID                       string                 `json:"id"`
+	Type                     TransactionType        `json:"type"`
+	Status                   TransactionStatus      `json:"status"`
+	PhoneNumber              string                 `json:"phone_number,omitempty"`
+	Amount                   int                    `json:"amount"`
+	AccountReference         string                 `json:"account_reference,omitempty"`
+	CommandID                string                 `json:"command_id,omitempty"`
+	MerchantRequestID        string                 `json:"merchant_request_id,omitempty"`
+	CheckoutRequestID        string                 `json:"checkout_request_id,omitempty"`
+	ConversationID           string                 `json:"conversation_id,omitempty"`
+	OriginatorConversationID string                 `json:"originator_conversation_id,omitempty"`
+	ReceiptNumber            string                 `json:"receipt_number,omitempty"`
+	ResultCode               *int                   `json:"result_code,omitempty"`
+	ResultDesc               string                 `json:"result_desc,omitempty"`
+	Metadata                 map[string]interface{} `json:"metadata,omitempty"`
+	ClientID                 string                 `json:"client_id,omitempty"` // API client that initiated it
+	CreatedAt                time.Time              `json:"created_at"`
+	UpdatedAt                time.Time              `json:"updated_at"`
+}
+
+// SetResult records the outcome Daraja reported for the transaction
+func (t *Transaction) SetResult(status TransactionStatus, resultCode int, resultDesc string) {
+	t.Status = status
+	t.ResultCode = &resultCode
+	t.ResultDesc = resultDesc
+}
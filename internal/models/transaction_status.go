@@ -0,0 +1,79 @@
+// ==========================
+// internal/models/transaction_status.go
+// ==========================
+package models
+
+// Client request model (snake_case for JSON); one of the identifiers is required
+type TransactionStatusRequest struct {
+	TransactionID            string `json:"transaction_id,omitempty"` // M-Pesa receipt number
+	OriginatorConversationID string `json:"originator_conversation_id,omitempty"`
+	Remarks                  string `json:"remarks" binding:"max=100"`
+}
+
+type TransactionStatusResponse struct {
+	ConversationID           string `json:"conversation_id"`
+	OriginatorConversationID string `json:"originator_conversation_id"`
+	ResponseCode             string `json:"response_code"`
+	ResponseDescription      string `json:"response_description"`
+}
+
+// M-Pesa callback models (PascalCase to match M-Pesa's response)
+type TransactionStatusCallback struct {
+	ResultType               int                  `json:"ResultType"`
+	ResultCode               int                  `json:"ResultCode"`
+	ResultDesc               string               `json:"ResultDesc"`
+	OriginatorConversationID string               `json:"OriginatorConversationID"`
+	ConversationID           string               `json:"ConversationID"`
+	TransactionID            string               `json:"TransactionID"`
+	ResultParameters         *B2CResultParameters `json:"ResultParameters,omitempty"`
+	ReferenceData            interface{}          `json:"ReferenceData,omitempty"`
+}
+
+type TransactionStatusResultRequest struct {
+	Result TransactionStatusCallback `json:"Result"`
+}
+
+// Helper method to extract result parameters as map
+func (cb *TransactionStatusCallback) GetResultParametersMap() map[string]interface{} {
+	result := make(map[string]interface{})
+	if cb.ResultParameters != nil {
+		for _, param := range cb.ResultParameters.ResultParameter {
+			result[param.Key] = param.Value
+		}
+	}
+	return result
+}
+
+// TransactionStatusFromMpesa maps the "TransactionStatus" result parameter to
+// our status. ok is false for states that are not final.
+func TransactionStatusFromMpesa(mpesaStatus string) (status TransactionStatus, ok bool) {
+	switch mpesaStatus {
+	case "Completed":
+		return TransactionStatusSuccess, true
+	case "Cancelled":
+		return TransactionStatusCancelled, true
+	case "Expired":
+		return TransactionStatusTimeout, true
+	case "Failed", "Declined":
+		return TransactionStatusFailed, true
+	case "Reversed":
+		return TransactionStatusReversed, true
+	default:
+		return "", false
+	}
+}
+
+// QueryMayMove reports whether a status query result may move a transaction
+// from one status to another. Held, rejected and reversed transactions are
+// settled by us, not by M-Pesa, and a transaction being reversed can only
+// become reversed.
+func QueryMayMove(from, to TransactionStatus) bool {
+	switch from {
+	case TransactionStatusAwaitingApproval, TransactionStatusRejected, TransactionStatusReversed:
+		return false
+	case TransactionStatusReversing:
+		return to == TransactionStatusReversed
+	default:
+		return true
+	}
+}
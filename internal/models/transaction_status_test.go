@@ -0,0 +1,57 @@
+// ==========================
+// internal/models/transaction_status_test.go
+// ==========================
+package models
+
+import "testing"
+
+func TestTransactionStatusFromMpesa(t *testing.T) {
+	tests := []struct {
+		mpesaStatus string
+		want        TransactionStatus
+		wantOK      bool
+	}{
+		{"Completed", TransactionStatusSuccess, true},
+		{"Cancelled", TransactionStatusCancelled, true},
+		{"Expired", TransactionStatusTimeout, true},
+		{"Failed", TransactionStatusFailed, true},
+		{"Declined", TransactionStatusFailed, true},
+		{"Reversed", TransactionStatusReversed, true},
+		{"Pending", "", false},
+		{"completed", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mpesaStatus, func(t *testing.T) {
+			got, ok := TransactionStatusFromMpesa(tt.mpesaStatus)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("TransactionStatusFromMpesa(%q) = %q, %v; want %q, %v", tt.mpesaStatus, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestQueryMayMove(t *testing.T) {
+	tests := []struct {
+		from, to TransactionStatus
+		want     bool
+	}{
+		{TransactionStatusPending, TransactionStatusSuccess, true},
+		{TransactionStatusTimeout, TransactionStatusFailed, true},
+		{TransactionStatusSuccess, TransactionStatusReversed, true},
+		{TransactionStatusAwaitingApproval, TransactionStatusSuccess, false},
+		{TransactionStatusRejected, TransactionStatusSuccess, false},
+		{TransactionStatusReversed, TransactionStatusSuccess, false},
+		{TransactionStatusReversing, TransactionStatusSuccess, false},
+		{TransactionStatusReversing, TransactionStatusReversed, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := QueryMayMove(tt.from, tt.to); got != tt.want {
+				t.Errorf("QueryMayMove(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// ==========================
+// internal/models/webhook.go
+// ==========================
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Client request model (snake_case for JSON)
+type WebhookEndpointRequest struct {
+	URL        string   `json:"url" binding:"required,url"` // https, on a public host
+	EventTypes []string `json:"event_types" binding:"required,min=1"`
+	Secret     string   `json:"secret,omitempty" binding:"omitempty,min=16"` // Generated when empty
+}
+
+// WebhookEndpoint is a partner URL notified of the listed event types
+type WebhookEndpoint struct {
+	ID         string      `json:"id"`
+	URL        string      `json:"url"`
+	EventTypes []EventType `json:"event_types"`
+	Secret     string      `json:"-"` // Signs deliveries; only shown when the endpoint is created
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// Wants reports whether the endpoint subscribed to eventType
+func (e *WebhookEndpoint) Wants(eventType EventType) bool {
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryDead      WebhookDeliveryStatus = "DEAD" // Out of retries, kept for redelivery
+)
+
+// WebhookDelivery is one event queued for one endpoint, along with every
+// attempt made to send it
+type WebhookDelivery struct {
+	ID            string                `json:"id"`
+	EndpointID    string                `json:"endpoint_id"`
+	EventID       string                `json:"event_id"`
+	EventType     EventType             `json:"event_type"`
+	Payload       json.RawMessage       `json:"payload"` // The event, exactly as signed and sent
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int                   `json:"attempts"` // Since it was queued or last redelivered
+	NextAttemptAt time.Time             `json:"next_attempt_at"`
+	History       []WebhookAttempt      `json:"history"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
+
+type WebhookAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
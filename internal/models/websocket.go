@@ -0,0 +1,13 @@
+// ==========================
+// internal/models/websocket.go
+// ==========================
+package models
+
+// Client request model (snake_case for JSON). The issued token only allows
+// subscribing to the transactions and event types listed here.
+type WSTokenRequest struct {
+	CheckoutRequestIDs        []string `json:"checkout_request_ids"`
+	OriginatorConversationIDs []string `json:"originator_conversation_ids"`
+	PhoneNumbers              []string `json:"phone_numbers"`
+	Types                     []string `json:"types"`
+}
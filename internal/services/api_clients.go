@@ -0,0 +1,147 @@
+// ==========================
+// internal/services/api_clients.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// API keys are "mpk_" followed by 32 random bytes in base64url. Only their
+// SHA-256 is stored; the first keyPrefixLength characters are kept in the
+// clear so operators can tell keys apart.
+const (
+	apiKeyPrefix    = "mpk_"
+	keyPrefixLength = 12
+)
+
+// AdminClientID identifies requests made with ADMIN_API_KEY. The admin key
+// can only manage API clients, never move money; since a creator can only
+// grant scopes it holds, clients that move money are created with
+// cmd/apiclient.
+const AdminClientID = "admin"
+
+var adminScopes = []models.Scope{models.ScopeClientsManage}
+
+// Bounds how far Lineage follows CreatedBy, in case of a cycle
+const maxLineageDepth = 32
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	ErrUnknownScope  = errors.New("unknown scope")
+	ErrScopeNotHeld  = errors.New("scope not held by the creating client")
+)
+
+// APIClientService issues API keys and resolves them back to their clients
+type APIClientService struct {
+	config *config.Config
+	store  storage.APIClientStore
+}
+
+func NewAPIClientService(cfg *config.Config, store storage.APIClientStore) *APIClientService {
+	return &APIClientService{
+		config: cfg,
+		store:  store,
+	}
+}
+
+// Create registers a client with the given scopes on behalf of creator, the
+// client making the request (nil from the CLI). A creator can only grant
+// scopes it holds itself. The returned key is the only copy; it cannot be
+// recovered later.
+func (s *APIClientService) Create(ctx context.Context, req models.APIClientRequest, creator *models.APIClient) (*models.APIClient, string, error) {
+	client := &models.APIClient{
+		ID:   uuid.New().String(),
+		Name: req.Name,
+	}
+	if creator != nil {
+		client.CreatedBy = creator.ID
+	}
+	for _, sc := range req.Scopes {
+		scope := models.Scope(sc)
+		if !scope.Valid() {
+			return nil, "", fmt.Errorf("%w: %s", ErrUnknownScope, sc)
+		}
+		if creator != nil && !creator.HasScope(scope) {
+			return nil, "", fmt.Errorf("%w: %s", ErrScopeNotHeld, sc)
+		}
+		if !client.HasScope(scope) {
+			client.Scopes = append(client.Scopes, scope)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	client.KeyPrefix = key[:keyPrefixLength]
+	client.KeyHash = hashAPIKey(key)
+
+	if err := s.store.Create(ctx, client); err != nil {
+		return nil, "", err
+	}
+	log.Printf("Created API client %s (%s) with scopes %v", client.ID, client.Name, client.Scopes)
+	return client, key, nil
+}
+
+// Authenticate returns the active client a key was issued to
+func (s *APIClientService) Authenticate(ctx context.Context, key string) (*models.APIClient, error) {
+	if s.config.AdminAPIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(s.config.AdminAPIKey)) == 1 {
+		return &models.APIClient{ID: AdminClientID, Name: AdminClientID, Scopes: adminScopes}, nil
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	client, err := s.store.GetByKeyHash(ctx, hashAPIKey(key))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if client.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	return client, nil
+}
+
+func (s *APIClientService) List(ctx context.Context) ([]*models.APIClient, error) {
+	return s.store.List(ctx)
+}
+
+func (s *APIClientService) Get(ctx context.Context, id string) (*models.APIClient, error) {
+	return s.store.GetByID(ctx, id)
+}
+
+// Revoke disables a client's key
+func (s *APIClientService) Revoke(ctx context.Context, id string) error {
+	if err := s.store.Revoke(ctx, id, time.Now()); err != nil {
+		return err
+	}
+	log.Printf("Revoked API client %s", id)
+	return nil
+}
+
+// hashAPIKey is unsalted: keys are long random strings, and the hash must be
+// deterministic to look a key up by it
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
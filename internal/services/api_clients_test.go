@@ -0,0 +1,42 @@
+// ==========================
+// internal/services/api_clients_test.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCreateRefusesScopeCreatorLacks(t *testing.T) {
+	service := NewAPIClientService(&config.Config{}, storage.NewMemoryAPIClientStore())
+	creator := &models.APIClient{ID: "creator", Scopes: []models.Scope{models.ScopeClientsManage, models.ScopeB2CPay}}
+
+	_, _, err := service.Create(context.Background(), models.APIClientRequest{
+		Name:   "checker",
+		Scopes: []string{string(models.ScopeB2CApprove)},
+	}, creator)
+	if !errors.Is(err, ErrScopeNotHeld) {
+		t.Errorf("Create() = %v, want %v", err, ErrScopeNotHeld)
+	}
+}
+
+func TestCreateRecordsCreator(t *testing.T) {
+	service := NewAPIClientService(&config.Config{}, storage.NewMemoryAPIClientStore())
+	creator := &models.APIClient{ID: "creator", Scopes: []models.Scope{models.ScopeClientsManage, models.ScopeB2CPay}}
+
+	client, _, err := service.Create(context.Background(), models.APIClientRequest{
+		Name:   "payer",
+		Scopes: []string{string(models.ScopeB2CPay)},
+	}, creator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.CreatedBy != "creator" {
+		t.Errorf("CreatedBy = %q, want creator", client.CreatedBy)
+	}
+}
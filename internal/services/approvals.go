@@ -0,0 +1,103 @@
+// ==========================
+// internal/services/approvals.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// PayoutApprovals is the maker-checker policy for B2C payouts: it decides
+// which payouts are held until a second API client approves them
+type PayoutApprovals struct {
+	config  *config.Config
+	repo    storage.TransactionRepository
+	clients storage.APIClientStore
+}
+
+func NewPayoutApprovals(cfg *config.Config, repo storage.TransactionRepository, clients storage.APIClientStore) *PayoutApprovals {
+	return &PayoutApprovals{
+		config:  cfg,
+		repo:    repo,
+		clients: clients,
+	}
+}
+
+// Reasons returns why req must be approved first; none means it can be sent
+func (a *PayoutApprovals) Reasons(ctx context.Context, req *models.B2CPaymentRequest) ([]models.ApprovalReason, error) {
+	var reasons []models.ApprovalReason
+	if a.config.B2CApprovalThreshold > 0 && req.Amount > a.config.B2CApprovalThreshold {
+		reasons = append(reasons, models.ApprovalReasonAmount)
+	}
+
+	if a.config.B2CApproveNewRecipients {
+		paid, err := a.repo.Totals(ctx, storage.TransactionFilter{
+			Type:        models.TransactionTypeB2C,
+			Statuses:    []models.TransactionStatus{models.TransactionStatusSuccess},
+			PhoneNumber: req.PhoneNumber,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up previous payouts: %w", err)
+		}
+		if paid.Count == 0 {
+			reasons = append(reasons, models.ApprovalReasonNewRecipient)
+		}
+	}
+	return reasons, nil
+}
+
+// ExpiresAt is when a payout held at heldAt can no longer be approved
+func (a *PayoutApprovals) ExpiresAt(heldAt time.Time) time.Time {
+	return heldAt.Add(time.Duration(a.config.B2CApprovalTTL) * time.Second)
+}
+
+// Expired reports whether a held payout has waited too long for a decision
+func (a *PayoutApprovals) Expired(tx *models.Transaction) bool {
+	return a.config.B2CApprovalTTL > 0 && time.Now().After(a.ExpiresAt(tx.CreatedAt))
+}
+
+// Independent reports whether checker may decide on a payout requested by
+// maker: neither may have created the other, directly or through clients
+// they created, and they may not share a creator, such as the admin key.
+// Clients created from the CLI have no creator.
+func (a *PayoutApprovals) Independent(ctx context.Context, maker, checker string) (bool, error) {
+	makers, err := a.lineage(ctx, maker)
+	if err != nil {
+		return false, err
+	}
+	checkers, err := a.lineage(ctx, checker)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range checkers {
+		if slices.Contains(makers, id) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// lineage returns id followed by the clients that created it, nearest first
+func (a *PayoutApprovals) lineage(ctx context.Context, id string) ([]string, error) {
+	var ids []string
+	for id != "" && len(ids) < maxLineageDepth && !slices.Contains(ids, id) {
+		ids = append(ids, id)
+		client, err := a.clients.GetByID(ctx, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			// The admin key and deleted clients end the chain
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up API client %s: %w", id, err)
+		}
+		id = client.CreatedBy
+	}
+	return ids, nil
+}
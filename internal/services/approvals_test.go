@@ -0,0 +1,70 @@
+// ==========================
+// internal/services/approvals_test.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"context"
+	"testing"
+)
+
+// storeClients stores one API client per id, created by the client it maps to
+func storeClients(t *testing.T, createdBy map[string]string) *PayoutApprovals {
+	t.Helper()
+	store := storage.NewMemoryAPIClientStore()
+	for id, creator := range createdBy {
+		client := &models.APIClient{ID: id, KeyHash: id, CreatedBy: creator}
+		if err := store.Create(context.Background(), client); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewPayoutApprovals(&config.Config{}, storage.NewMemoryRepository(), store)
+}
+
+func TestIndependentClientsFromCLI(t *testing.T) {
+	approvals := storeClients(t, map[string]string{"maker": "", "checker": ""})
+
+	independent, err := approvals.Independent(context.Background(), "maker", "checker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !independent {
+		t.Error("Independent() = false for clients created from the CLI, want true")
+	}
+}
+
+func TestIndependentRejectsRelatedClients(t *testing.T) {
+	approvals := storeClients(t, map[string]string{
+		"maker":       "",
+		"child":       "maker",
+		"grandchild":  "child",
+		"sibling-a":   AdminClientID,
+		"sibling-b":   AdminClientID,
+		"unrelated":   "",
+		"nephew":      "unrelated",
+		"cycle-start": "cycle-end",
+		"cycle-end":   "cycle-start",
+	})
+
+	for _, pair := range [][2]string{
+		{"maker", "child"},
+		{"grandchild", "maker"},
+		{"sibling-a", "sibling-b"},
+		{"cycle-start", "cycle-end"},
+	} {
+		independent, err := approvals.Independent(context.Background(), pair[0], pair[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if independent {
+			t.Errorf("Independent(%s, %s) = true, want false", pair[0], pair[1])
+		}
+	}
+
+	if independent, _ := approvals.Independent(context.Background(), "grandchild", "nephew"); !independent {
+		t.Error("Independent(grandchild, nephew) = false, want true")
+	}
+}
@@ -4,67 +4,121 @@
 package services
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"sync"
 	"time"
 
 	"awesomeProject/internal/config"
-)
+	"awesomeProject/internal/storage"
 
-type TokenCache struct {
-	token  string
-	expiry time.Time
-	mu     sync.RWMutex
-}
+	"golang.org/x/sync/singleflight"
+)
 
-var tokenCache = &TokenCache{}
+const (
+	// Held by the instance fetching a new token; also bounds how long others wait for it
+	tokenRefreshTimeout = 30 * time.Second
+	tokenLockName       = "daraja_access_token"
+	tokenLockPoll       = 250 * time.Millisecond
+)
 
-func (tc *TokenCache) Set(token string, expiresIn int) {
-	tc.mu.Lock()
-	defer tc.mu.Unlock()
-	tc.token = token
-	tc.expiry = time.Now().Add(time.Duration(expiresIn-60) * time.Second)
+type AuthService struct {
+	config     *config.Config
+	httpClient *http.Client
+	tokens     storage.TokenStore
+	locks      storage.Locker
+	refreshes  singleflight.Group // Concurrent callers share one OAuth request
 }
 
-func (tc *TokenCache) Get() (string, bool) {
-	tc.mu.RLock()
-	defer tc.mu.RUnlock()
-	if tc.token != "" && time.Now().Before(tc.expiry) {
-		return tc.token, true
+func NewAuthService(cfg *config.Config, httpClient *http.Client, tokens storage.TokenStore, locks storage.Locker) *AuthService {
+	return &AuthService{
+		config:     cfg,
+		httpClient: httpClient,
+		tokens:     tokens,
+		locks:      locks,
 	}
-	return "", false
 }
 
-func (tc *TokenCache) Clear() {
-	tc.mu.Lock()
-	defer tc.mu.Unlock()
-	tc.token = ""
-	tc.expiry = time.Time{}
+func (s *AuthService) GetAccessToken(forceRefresh bool) (string, error) {
+	token := s.cachedToken(context.Background())
+	if token != "" && !forceRefresh {
+		return token, nil
+	}
+	return s.refresh(token)
 }
 
-type AuthService struct {
-	config *config.Config
+// RefreshAccessToken replaces a token Daraja rejected. If another caller has
+// already replaced it, the newer cached token is returned without a new
+// OAuth request.
+func (s *AuthService) RefreshAccessToken(stale string) (string, error) {
+	if token := s.cachedToken(context.Background()); token != "" && token != stale {
+		return token, nil
+	}
+	return s.refresh(stale)
 }
 
-func NewAuthService(cfg *config.Config) *AuthService {
-	return &AuthService{config: cfg}
+// cachedToken returns the stored token, or "" if there is none. A store that
+// cannot be reached is treated as empty so payments keep working.
+func (s *AuthService) cachedToken(ctx context.Context) string {
+	token, ok, err := s.tokens.Get(ctx)
+	if err != nil {
+		log.Printf("Token store unavailable: %v", err)
+		return ""
+	}
+	if !ok {
+		return ""
+	}
+	return token
 }
 
-func (s *AuthService) GetAccessToken(forceRefresh bool) (string, error) {
-	if !forceRefresh {
-		if token, ok := tokenCache.Get(); ok {
-			return token, nil
+// refresh replaces stale with a new token. Only one instance fetches at a
+// time; the others wait for it to appear in the token store.
+func (s *AuthService) refresh(stale string) (string, error) {
+	token, err, _ := s.refreshes.Do("access_token", func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), tokenRefreshTimeout)
+		defer cancel()
+
+		for {
+			if token := s.cachedToken(ctx); token != "" && token != stale {
+				return token, nil
+			}
+
+			release, acquired, err := s.locks.TryLock(ctx, tokenLockName, tokenRefreshTimeout)
+			if err != nil {
+				log.Printf("Refreshing access token without lock: %v", err)
+				return s.fetchAccessToken(ctx)
+			}
+			if acquired {
+				defer release()
+				// Another instance may have stored a token since we last looked
+				if token := s.cachedToken(ctx); token != "" && token != stale {
+					return token, nil
+				}
+				return s.fetchAccessToken(ctx)
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("timed out waiting for another instance to refresh the access token")
+			case <-time.After(tokenLockPoll):
+			}
 		}
+	})
+	if err != nil {
+		return "", err
 	}
+	return token.(string), nil
+}
 
+func (s *AuthService) fetchAccessToken(ctx context.Context) (string, error) {
 	authString := fmt.Sprintf("%s:%s", s.config.ConsumerKey, s.config.ConsumerSecret)
 	encoded := base64.StdEncoding.EncodeToString([]byte(authString))
 
-	req, err := http.NewRequest("GET", s.config.OAuthURL(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", s.config.OAuthURL(), nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -72,8 +126,7 @@ func (s *AuthService) GetAccessToken(forceRefresh bool) (string, error) {
 	req.Header.Set("Authorization", "Basic "+encoded)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: time.Duration(s.config.APITimeout) * time.Second}
-	resp, err := client.Do(req)
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to get access token: %w", err)
 	}
@@ -106,6 +159,13 @@ func (s *AuthService) GetAccessToken(forceRefresh bool) (string, error) {
 		fmt.Sscanf(result.ExpiresIn, "%d", &expiresIn)
 	}
 
-	tokenCache.Set(result.AccessToken, expiresIn)
+	// Stop using the token a minute before Daraja expires it
+	ttl := time.Duration(expiresIn-60) * time.Second
+	if ttl <= 0 {
+		ttl = time.Duration(expiresIn) * time.Second
+	}
+	if err := s.tokens.Set(ctx, result.AccessToken, ttl); err != nil {
+		log.Printf("Failed to cache access token: %v", err)
+	}
 	return result.AccessToken, nil
 }
@@ -0,0 +1,66 @@
+// ==========================
+// internal/services/b2b.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/daraja"
+	"awesomeProject/internal/models"
+	"context"
+	"fmt"
+)
+
+type B2BService struct {
+	config    *config.Config
+	client    *daraja.Client
+	callbacks *CallbackTokens
+}
+
+func NewB2BService(cfg *config.Config, client *daraja.Client, callbacks *CallbackTokens) *B2BService {
+	return &B2BService{
+		config:    cfg,
+		client:    client,
+		callbacks: callbacks,
+	}
+}
+
+func (s *B2BService) InitiatePayment(ctx context.Context, req *models.B2BPaymentRequest, ref string) (*models.B2BPaymentResponse, error) {
+	// Encrypt initiator password
+	credential, err := securityCredential(s.config)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.client.B2BPayment(ctx, &daraja.B2BPaymentRequest{
+		OriginatorConversationID: req.OriginatorConversationID,
+		Initiator:                s.config.InitiatorName,
+		SecurityCredential:       credential,
+		CommandID:                req.CommandID,
+		SenderIdentifierType:     daraja.IdentifierTypeShortCode,
+		ReceiverIdentifierType:   daraja.IdentifierTypeShortCode,
+		Amount:                   req.Amount,
+		PartyA:                   fmt.Sprintf("%d", s.config.BusinessShortCode),
+		PartyB:                   req.ReceiverShortCode,
+		AccountReference:         req.AccountReference,
+		Requester:                req.Requester,
+		Remarks:                  req.Remarks,
+		QueueTimeOutURL:          s.callbacks.URL(s.config.B2BTimeoutURL, ref),
+		ResultURL:                s.callbacks.URL(s.config.B2BResultURL, ref),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Daraja may assign its own OriginatorConversationID; callbacks carry that one
+	if result.OriginatorConversationID == "" {
+		result.OriginatorConversationID = req.OriginatorConversationID
+	}
+
+	return &models.B2BPaymentResponse{
+		ConversationID:           result.ConversationID,
+		OriginatorConversationID: result.OriginatorConversationID,
+		ResponseCode:             result.ResponseCode,
+		ResponseDescription:      result.ResponseDescription,
+	}, nil
+}
@@ -1,107 +1,52 @@
 // ==========================
-// internal/services/b2c.go (FIXED)
+// internal/services/b2c.go
 // ==========================
 package services
 
 import (
 	"awesomeProject/internal/config"
+	"awesomeProject/internal/daraja"
 	"awesomeProject/internal/models"
-	"awesomeProject/internal/utils"
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
-	"time"
 )
 
 type B2CService struct {
-	config      *config.Config
-	authService *AuthService
+	config    *config.Config
+	client    *daraja.Client
+	callbacks *CallbackTokens
 }
 
-func NewB2CService(cfg *config.Config, authService *AuthService) *B2CService {
+func NewB2CService(cfg *config.Config, client *daraja.Client, callbacks *CallbackTokens) *B2CService {
 	return &B2CService{
-		config:      cfg,
-		authService: authService,
+		config:    cfg,
+		client:    client,
+		callbacks: callbacks,
 	}
 }
 
-func (s *B2CService) InitiatePayment(req *models.B2CPaymentRequest) (*models.B2CPaymentResponse, error) {
-	// Get access token
-	accessToken, err := s.authService.GetAccessToken(false)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get access token: %w", err)
-	}
-
+func (s *B2CService) InitiatePayment(ctx context.Context, req *models.B2CPaymentRequest, ref string) (*models.B2CPaymentResponse, error) {
 	// Encrypt initiator password
-	securityCredential, err := utils.EncryptInitiatorPassword(
-		s.config.InitiatorPassword,
-		s.config.CertificatePath,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt password: %w", err)
-	}
-
-	// Prepare request payload - note "Occassion" typo matches M-Pesa API
-	payload := map[string]interface{}{
-		"OriginatorConversationID": req.OriginatorConversationID,
-		"InitiatorName":            s.config.InitiatorName,
-		"SecurityCredential":       securityCredential,
-		"CommandID":                req.CommandID,
-		"Amount":                   req.Amount,
-		"PartyA":                   fmt.Sprintf("%d", s.config.BusinessShortCode),
-		"PartyB":                   req.PhoneNumber,
-		"Remarks":                  req.Remarks,
-		"QueueTimeOutURL":          s.config.B2CTimeoutURL,
-		"ResultURL":                s.config.B2CResultURL,
-		"Occassion":                req.Occasion, // Typo in M-Pesa API
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Make HTTP request
-	httpReq, err := http.NewRequest("POST", s.config.B2CURL(), bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: time.Duration(s.config.APITimeout) * time.Second}
-	resp, err := client.Do(httpReq)
+	credential, err := securityCredential(s.config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+		return nil, err
+	}
+
+	result, err := s.client.B2CPayment(ctx, &daraja.B2CPaymentRequest{
+		OriginatorConversationID: req.OriginatorConversationID,
+		InitiatorName:            s.config.InitiatorName,
+		SecurityCredential:       credential,
+		CommandID:                req.CommandID,
+		Amount:                   req.Amount,
+		PartyA:                   fmt.Sprintf("%d", s.config.BusinessShortCode),
+		PartyB:                   req.PhoneNumber,
+		Remarks:                  req.Remarks,
+		QueueTimeOutURL:          s.callbacks.URL(s.config.B2CTimeoutURL, ref),
+		ResultURL:                s.callbacks.URL(s.config.B2CResultURL, ref),
+		Occasion:                 req.Occasion,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Log response for debugging
-	log.Printf("M-Pesa B2C Response: Status=%d, Body=%s", resp.StatusCode, string(body))
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response - M-Pesa uses PascalCase
-	var result struct {
-		ConversationID           string `json:"ConversationID"`
-		OriginatorConversationID string `json:"OriginatorConversationID"`
-		ResponseCode             string `json:"ResponseCode"`
-		ResponseDescription      string `json:"ResponseDescription"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, err
 	}
 
 	return &models.B2CPaymentResponse{
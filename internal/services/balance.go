@@ -0,0 +1,107 @@
+// ==========================
+// internal/services/balance.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/daraja"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+type AccountBalanceService struct {
+	config *config.Config
+	client *daraja.Client
+	store  storage.BalanceStore
+}
+
+func NewAccountBalanceService(cfg *config.Config, client *daraja.Client, store storage.BalanceStore) *AccountBalanceService {
+	return &AccountBalanceService{
+		config: cfg,
+		client: client,
+		store:  store,
+	}
+}
+
+// RequestBalance asks Daraja for the shortcode's balances. The answer arrives
+// asynchronously on AccountBalanceResultURL.
+func (s *AccountBalanceService) RequestBalance(ctx context.Context) (*models.AccountBalanceResponse, error) {
+	credential, err := securityCredential(s.config)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.client.AccountBalance(ctx, &daraja.AccountBalanceRequest{
+		Initiator:          s.config.InitiatorName,
+		SecurityCredential: credential,
+		CommandID:          "AccountBalance",
+		PartyA:             fmt.Sprintf("%d", s.config.BusinessShortCode),
+		IdentifierType:     daraja.IdentifierTypeShortCode,
+		Remarks:            "Balance query",
+		QueueTimeOutURL:    s.config.AccountBalanceTimeoutURL,
+		ResultURL:          s.config.AccountBalanceResultURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AccountBalanceResponse{
+		ConversationID:           result.ConversationID,
+		OriginatorConversationID: result.OriginatorConversationID,
+		ResponseCode:             result.ResponseCode,
+		ResponseDescription:      result.ResponseDescription,
+	}, nil
+}
+
+func (s *AccountBalanceService) SetSnapshot(ctx context.Context, snapshot *models.BalanceSnapshot) error {
+	return s.store.Set(ctx, snapshot)
+}
+
+// Snapshot returns the latest known balances, or nil if none arrived yet
+func (s *AccountBalanceService) Snapshot(ctx context.Context) (*models.BalanceSnapshot, error) {
+	return s.store.Get(ctx)
+}
+
+// PayoutAvailable returns the available balance of the payout account. ok is
+// false when there is no snapshot recent enough to rely on.
+func (s *AccountBalanceService) PayoutAvailable(ctx context.Context) (available float64, ok bool) {
+	snapshot, err := s.Snapshot(ctx)
+	if err != nil {
+		log.Printf("Failed to load balance snapshot: %v", err)
+		return 0, false
+	}
+	if snapshot == nil {
+		return 0, false
+	}
+	if time.Since(snapshot.RetrievedAt) > time.Duration(s.config.BalanceMaxAge)*time.Second {
+		return 0, false
+	}
+
+	account, ok := snapshot.Account(s.config.PayoutAccount)
+	if !ok {
+		return 0, false
+	}
+	return account.Available, true
+}
+
+// Run refreshes the balance every BalanceRefreshInterval seconds
+func (s *AccountBalanceService) Run() {
+	if s.config.BalanceRefreshInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.config.BalanceRefreshInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RequestBalance(context.Background()); err != nil {
+			log.Printf("Scheduled balance query failed: %v", err)
+		}
+		<-ticker.C
+	}
+}
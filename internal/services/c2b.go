@@ -0,0 +1,121 @@
+// ==========================
+// internal/services/c2b.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/daraja"
+	"awesomeProject/internal/models"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+type C2BService struct {
+	config *config.Config
+	client *daraja.Client
+}
+
+func NewC2BService(cfg *config.Config, client *daraja.Client) *C2BService {
+	return &C2BService{
+		config: cfg,
+		client: client,
+	}
+}
+
+// RegisterURLs tells M-Pesa where to send validation and confirmation requests
+// for payments made directly to our shortcode
+func (s *C2BService) RegisterURLs(ctx context.Context) (*models.C2BRegisterURLResponse, error) {
+	result, err := s.client.RegisterC2BURLs(ctx, &daraja.C2BRegisterURLRequest{
+		ShortCode:       strconv.Itoa(s.config.BusinessShortCode),
+		ResponseType:    s.config.C2BResponseType,
+		ConfirmationURL: s.config.C2BConfirmationURL,
+		ValidationURL:   s.config.C2BValidationURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.C2BRegisterURLResponse{
+		OriginatorConversationID: result.OriginatorConversationID,
+		ResponseCode:             result.ResponseCode,
+		ResponseDescription:      result.ResponseDescription,
+	}, nil
+}
+
+// C2BRejection explains why a C2B payment failed validation
+type C2BRejection struct {
+	ResultCode string
+	Reason     string
+}
+
+// C2BRule decides whether an incoming C2B payment may be accepted. It returns
+// nil to accept the payment.
+type C2BRule interface {
+	Validate(payment *models.C2BPayment) *C2BRejection
+}
+
+// AccountReferenceRule rejects payments whose bill reference does not match Pattern
+type AccountReferenceRule struct {
+	Pattern *regexp.Regexp
+}
+
+func (r AccountReferenceRule) Validate(payment *models.C2BPayment) *C2BRejection {
+	if !r.Pattern.MatchString(payment.BillRefNumber) {
+		return &C2BRejection{
+			ResultCode: models.C2BResultInvalidAccount,
+			Reason:     fmt.Sprintf("account reference %q is not valid", payment.BillRefNumber),
+		}
+	}
+	return nil
+}
+
+// AmountLimitRule rejects payments outside [Min, Max]. A zero bound is not enforced.
+type AmountLimitRule struct {
+	Min float64
+	Max float64
+}
+
+func (r AmountLimitRule) Validate(payment *models.C2BPayment) *C2BRejection {
+	amount, err := strconv.ParseFloat(payment.TransAmount, 64)
+	if err != nil {
+		return &C2BRejection{
+			ResultCode: models.C2BResultInvalidAmount,
+			Reason:     fmt.Sprintf("amount %q is not a number", payment.TransAmount),
+		}
+	}
+	if r.Min > 0 && amount < r.Min {
+		return &C2BRejection{
+			ResultCode: models.C2BResultInvalidAmount,
+			Reason:     fmt.Sprintf("amount %.2f is below the minimum of %.2f", amount, r.Min),
+		}
+	}
+	if r.Max > 0 && amount > r.Max {
+		return &C2BRejection{
+			ResultCode: models.C2BResultInvalidAmount,
+			Reason:     fmt.Sprintf("amount %.2f is above the maximum of %.2f", amount, r.Max),
+		}
+	}
+	return nil
+}
+
+// C2BRulesFromConfig builds the validation rules enabled in configuration
+func C2BRulesFromConfig(cfg *config.Config) ([]C2BRule, error) {
+	rules := make([]C2BRule, 0)
+
+	if cfg.C2BAccountPattern != "" {
+		pattern, err := regexp.Compile(cfg.C2BAccountPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid C2B_ACCOUNT_PATTERN: %w", err)
+		}
+		rules = append(rules, AccountReferenceRule{Pattern: pattern})
+	}
+
+	if cfg.C2BMinAmount > 0 || cfg.C2BMaxAmount > 0 {
+		rules = append(rules, AmountLimitRule{Min: cfg.C2BMinAmount, Max: cfg.C2BMaxAmount})
+	}
+
+	return rules, nil
+}
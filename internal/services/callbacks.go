@@ -0,0 +1,61 @@
+// ==========================
+// internal/services/callbacks.go
+// ==========================
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"strings"
+)
+
+// CallbackTokens makes the callback URLs sent to Daraja unique to each
+// transaction. The token appended to a URL is ref "." base64url(HMAC-SHA256),
+// where ref is the ID we store the transaction under, so a callback can only
+// be posted by someone Daraja told the URL to, and only about that transaction.
+type CallbackTokens struct {
+	secret []byte
+}
+
+// NewCallbackTokens returns tokens signed with secret. Without a secret no
+// tokens are issued and callbacks are accepted on the bare URLs.
+func NewCallbackTokens(secret string) *CallbackTokens {
+	if secret == "" {
+		log.Println("Warning: CALLBACK_TOKEN_SECRET not set, callback URLs are not tied to transactions")
+		return &CallbackTokens{}
+	}
+	return &CallbackTokens{secret: []byte(secret)}
+}
+
+// Enabled reports whether callbacks must carry a token
+func (t *CallbackTokens) Enabled() bool {
+	return len(t.secret) > 0
+}
+
+// URL appends the token for ref to callbackURL as a final path segment
+func (t *CallbackTokens) URL(callbackURL, ref string) string {
+	if !t.Enabled() || callbackURL == "" || ref == "" {
+		return callbackURL
+	}
+	return strings.TrimSuffix(callbackURL, "/") + "/" + ref + "." + t.sign(ref)
+}
+
+// Verify returns the transaction ref a token was issued for
+func (t *CallbackTokens) Verify(token string) (string, bool) {
+	ref, signature, ok := strings.Cut(token, ".")
+	if !t.Enabled() || !ok || ref == "" {
+		return "", false
+	}
+	if !hmac.Equal([]byte(signature), []byte(t.sign(ref))) {
+		return "", false
+	}
+	return ref, true
+}
+
+func (t *CallbackTokens) sign(ref string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte("callback:" + ref))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
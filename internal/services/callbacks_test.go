@@ -0,0 +1,53 @@
+// ==========================
+// internal/services/callbacks_test.go
+// ==========================
+package services
+
+import (
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestCallbackTokensVerifiesOwnURL(t *testing.T) {
+	tokens := NewCallbackTokens("secret")
+	url := tokens.URL("https://example.com/api/v1/stk/callback/", "tx-1")
+
+	if !strings.HasPrefix(url, "https://example.com/api/v1/stk/callback/tx-1.") {
+		t.Errorf("URL() = %q, want the token appended as a path segment", url)
+	}
+	if ref, ok := tokens.Verify(path.Base(url)); !ok || ref != "tx-1" {
+		t.Errorf("Verify() = %q, %v; want tx-1, true", ref, ok)
+	}
+}
+
+func TestCallbackTokensRejectsForgedToken(t *testing.T) {
+	tokens := NewCallbackTokens("secret")
+	token := path.Base(tokens.URL("https://example.com", "tx-1"))
+	signature := strings.TrimPrefix(token, "tx-1")
+
+	for _, forged := range []string{
+		"tx-2" + signature,
+		path.Base(NewCallbackTokens("other secret").URL("https://example.com", "tx-1")),
+		token + "x",
+		"tx-1",
+		signature,
+		"",
+	} {
+		if ref, ok := tokens.Verify(forged); ok {
+			t.Errorf("Verify(%q) = %q, true; want it rejected", forged, ref)
+		}
+	}
+}
+
+func TestCallbackTokensDisabledWithoutSecret(t *testing.T) {
+	tokens := &CallbackTokens{}
+
+	if got := tokens.URL("https://example.com/callback", "tx-1"); got != "https://example.com/callback" {
+		t.Errorf("URL() = %q, want the bare URL", got)
+	}
+	token := path.Base(NewCallbackTokens("secret").URL("https://example.com", "tx-1"))
+	if _, ok := tokens.Verify(token); ok {
+		t.Error("Verify() accepted a token without a secret configured")
+	}
+}
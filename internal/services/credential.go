@@ -0,0 +1,19 @@
+// ==========================
+// internal/services/credential.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/utils"
+	"fmt"
+)
+
+// securityCredential encrypts the initiator password for APIs acting on behalf of the initiator
+func securityCredential(cfg *config.Config) (string, error) {
+	credential, err := utils.EncryptInitiatorPassword(cfg.InitiatorPassword, cfg.CertificatePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt password: %w", err)
+	}
+	return credential, nil
+}
@@ -0,0 +1,110 @@
+// ==========================
+// internal/services/crosscheck.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CrossCheckSTK compares the CallbackMetadata of an STK callback with the
+// push we initiated. A successful callback (resultCode 0) must carry Amount
+// and PhoneNumber; on a failed one, missing fields are not checked.
+func CrossCheckSTK(tx *models.Transaction, resultCode int, metadata map[string]interface{}) []models.CallbackMismatch {
+	var mismatches []models.CallbackMismatch
+	if amount, ok := metadata["Amount"]; ok {
+		mismatches = checkAmount(mismatches, "Amount", tx.Amount, amount)
+	} else if resultCode == 0 {
+		mismatches = missingField(mismatches, "Amount", strconv.Itoa(tx.Amount))
+	}
+	if phone, ok := metadata["PhoneNumber"]; ok {
+		mismatches = checkPhone(mismatches, "PhoneNumber", tx.PhoneNumber, phone)
+	} else if resultCode == 0 {
+		mismatches = missingField(mismatches, "PhoneNumber", tx.PhoneNumber)
+	}
+	return mismatches
+}
+
+// CrossCheckB2C compares the ResultParameters of a B2C result with the
+// payment we initiated. A successful result must carry TransactionAmount and
+// ReceiverPartyPublicName.
+func CrossCheckB2C(tx *models.Transaction, resultCode int, params map[string]interface{}) []models.CallbackMismatch {
+	var mismatches []models.CallbackMismatch
+	if amount, ok := params["TransactionAmount"]; ok {
+		mismatches = checkAmount(mismatches, "TransactionAmount", tx.Amount, amount)
+	} else if resultCode == 0 {
+		mismatches = missingField(mismatches, "TransactionAmount", strconv.Itoa(tx.Amount))
+	}
+	// "254708374149 - John Doe", with the number partly masked in production
+	if receiver, ok := params["ReceiverPartyPublicName"]; ok {
+		phone, _, _ := strings.Cut(callbackString(receiver), " - ")
+		mismatches = checkPhone(mismatches, "ReceiverPartyPublicName", tx.PhoneNumber, phone)
+	} else if resultCode == 0 {
+		mismatches = missingField(mismatches, "ReceiverPartyPublicName", tx.PhoneNumber)
+	}
+	return mismatches
+}
+
+// missingField reports a field a successful callback must carry but did not
+func missingField(mismatches []models.CallbackMismatch, field, expected string) []models.CallbackMismatch {
+	return append(mismatches, models.CallbackMismatch{
+		Field:    field,
+		Expected: expected,
+		Received: "",
+	})
+}
+
+func checkAmount(mismatches []models.CallbackMismatch, field string, expected int, received interface{}) []models.CallbackMismatch {
+	value := callbackString(received)
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || amount != float64(expected) {
+		mismatches = append(mismatches, models.CallbackMismatch{
+			Field:    field,
+			Expected: strconv.Itoa(expected),
+			Received: value,
+		})
+	}
+	return mismatches
+}
+
+func checkPhone(mismatches []models.CallbackMismatch, field, expected string, received interface{}) []models.CallbackMismatch {
+	value := strings.TrimSpace(callbackString(received))
+	if expected == "" || !phoneMatches(expected, value) {
+		mismatches = append(mismatches, models.CallbackMismatch{
+			Field:    field,
+			Expected: expected,
+			Received: value,
+		})
+	}
+	return mismatches
+}
+
+// phoneMatches compares a 254XXXXXXXXX number with one from a callback,
+// where Safaricom may mask the middle digits with '*'
+func phoneMatches(expected, received string) bool {
+	first, last := strings.Index(received, "*"), strings.LastIndex(received, "*")
+	if first < 0 {
+		formatted, err := utils.FormatPhoneNumber(received)
+		return err == nil && formatted == expected
+	}
+
+	prefix, suffix := strings.TrimPrefix(received[:first], "+"), received[last+1:]
+	if strings.HasPrefix(prefix, "0") {
+		prefix = "254" + prefix[1:]
+	}
+	return len(prefix)+len(suffix) > 0 &&
+		strings.HasPrefix(expected, prefix) && strings.HasSuffix(expected, suffix)
+}
+
+// callbackString renders a JSON value from a callback; numbers such as
+// 254708374149 are printed without an exponent
+func callbackString(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,119 @@
+// ==========================
+// internal/services/crosscheck_test.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestPhoneMatches(t *testing.T) {
+	for _, received := range []string{
+		"254708374149", "0708374149", "+254708374149",
+		"2547****4149", "0708***149", "+25470*****49",
+	} {
+		if !phoneMatches("254708374149", received) {
+			t.Errorf("phoneMatches(254708374149, %q) = false, want true", received)
+		}
+	}
+
+	for _, received := range []string{"254708374148", "2547****4148", "2548****4149", "*****", ""} {
+		if phoneMatches("254708374149", received) {
+			t.Errorf("phoneMatches(254708374149, %q) = true, want false", received)
+		}
+	}
+}
+
+func TestCrossCheckSTKMatches(t *testing.T) {
+	tx := &models.Transaction{Amount: 100, PhoneNumber: "254708374149"}
+
+	exact := map[string]interface{}{"Amount": float64(100), "PhoneNumber": float64(254708374149)}
+	if got := CrossCheckSTK(tx, 0, exact); got != nil {
+		t.Errorf("CrossCheckSTK() = %+v, want no mismatches", got)
+	}
+	masked := map[string]interface{}{"Amount": "100.00", "PhoneNumber": "2547****4149"}
+	if got := CrossCheckSTK(tx, 0, masked); got != nil {
+		t.Errorf("CrossCheckSTK() with a masked phone = %+v, want no mismatches", got)
+	}
+}
+
+func TestCrossCheckSTKMismatches(t *testing.T) {
+	tx := &models.Transaction{Amount: 100, PhoneNumber: "254708374149"}
+
+	got := CrossCheckSTK(tx, 0, map[string]interface{}{"Amount": float64(1), "PhoneNumber": float64(254700000000)})
+
+	want := []models.CallbackMismatch{
+		{Field: "Amount", Expected: "100", Received: "1"},
+		{Field: "PhoneNumber", Expected: "254708374149", Received: "254700000000"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CrossCheckSTK() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCrossCheckSTKMissingMetadata(t *testing.T) {
+	tx := &models.Transaction{Amount: 100, PhoneNumber: "254708374149"}
+
+	want := []models.CallbackMismatch{
+		{Field: "Amount", Expected: "100"},
+		{Field: "PhoneNumber", Expected: "254708374149"},
+	}
+	if got := CrossCheckSTK(tx, 0, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("CrossCheckSTK() on success = %+v, want %+v", got, want)
+	}
+	// Failed payments carry no metadata
+	if got := CrossCheckSTK(tx, 1032, nil); got != nil {
+		t.Errorf("CrossCheckSTK() on failure = %+v, want no mismatches", got)
+	}
+}
+
+func TestCrossCheckB2CMatches(t *testing.T) {
+	tx := &models.Transaction{Amount: 500, PhoneNumber: "254708374149"}
+
+	got := CrossCheckB2C(tx, 0, map[string]interface{}{
+		"TransactionAmount":       float64(500),
+		"ReceiverPartyPublicName": "254708374149 - John Doe",
+	})
+
+	if got != nil {
+		t.Errorf("CrossCheckB2C() = %+v, want no mismatches", got)
+	}
+}
+
+func TestCrossCheckB2CMismatches(t *testing.T) {
+	tx := &models.Transaction{Amount: 500, PhoneNumber: "254708374149"}
+
+	got := CrossCheckB2C(tx, 0, map[string]interface{}{
+		"TransactionAmount":       float64(5000),
+		"ReceiverPartyPublicName": "0711111111 - Jane Doe",
+	})
+
+	want := []models.CallbackMismatch{
+		{Field: "TransactionAmount", Expected: "500", Received: "5000"},
+		{Field: "ReceiverPartyPublicName", Expected: "254708374149", Received: "0711111111"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CrossCheckB2C() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCrossCheckB2CMissingReceiver(t *testing.T) {
+	tx := &models.Transaction{Amount: 500, PhoneNumber: "254708374149"}
+
+	got := CrossCheckB2C(tx, 0, map[string]interface{}{"TransactionAmount": float64(500)})
+
+	want := []models.CallbackMismatch{{Field: "ReceiverPartyPublicName", Expected: "254708374149"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CrossCheckB2C() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCrossCheckB2CFailureIgnoresMissingParameters(t *testing.T) {
+	tx := &models.Transaction{Amount: 500, PhoneNumber: "254708374149"}
+
+	if got := CrossCheckB2C(tx, 2001, nil); got != nil {
+		t.Errorf("CrossCheckB2C() = %+v, want no mismatches", got)
+	}
+}
@@ -0,0 +1,271 @@
+// ==========================
+// internal/services/payout_rules.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"awesomeProject/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule identifiers reported in PayoutViolation.Rule
+const (
+	RuleBlockedMSISDN     = "blocked_msisdn"
+	RuleCommandMax        = "command_max"
+	RuleRecipientDailyCap = "recipient_daily_cap"
+	RuleClientHourlyCap   = "client_hourly_cap"
+)
+
+// A payout's lock is held from evaluating its rules until it is stored, which
+// happens before Daraja is called; payoutLockWait bounds how long a payout
+// waits for another one to the same phone number or client.
+const (
+	payoutLockTTL  = 30 * time.Second
+	payoutLockWait = 5 * time.Second
+	payoutLockPoll = 50 * time.Millisecond
+)
+
+// ErrPayoutBusy means another payout to the same phone number or client held
+// its lock for longer than payoutLockWait
+var ErrPayoutBusy = errors.New("another payout for this phone number or client is in progress")
+
+// Payouts in these states may have moved money, so they count towards caps
+var committedStatuses = []models.TransactionStatus{
+	models.TransactionStatusPending,
+	models.TransactionStatusSuccess,
+	models.TransactionStatusTimeout,
+	models.TransactionStatusAwaitingApproval,
+	models.TransactionStatusReversing,
+}
+
+// Payout is a payment about to be requested from Daraja
+type Payout struct {
+	Type        models.TransactionType
+	PhoneNumber string // Formatted as 254XXXXXXXXX
+	Amount      int
+	CommandID   string
+	ClientID    string
+	Held        bool // Stored awaiting approval, so the caps already count it
+}
+
+// PayoutViolation explains why a payout was refused. Limit and Used are set
+// for the rules that have them.
+type PayoutViolation struct {
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+	Limit  int    `json:"limit,omitempty"`
+	Used   int    `json:"used,omitempty"` // Already committed in the rule's window
+}
+
+// PayoutRule decides whether a payout may go ahead. It returns nil to allow it.
+type PayoutRule interface {
+	Evaluate(ctx context.Context, payout *Payout) (*PayoutViolation, error)
+}
+
+// BlockedMSISDNRule refuses payouts involving the listed phone numbers
+type BlockedMSISDNRule struct {
+	Numbers map[string]bool
+}
+
+func (r BlockedMSISDNRule) Evaluate(ctx context.Context, payout *Payout) (*PayoutViolation, error) {
+	if r.Numbers[payout.PhoneNumber] {
+		return &PayoutViolation{
+			Rule:   RuleBlockedMSISDN,
+			Reason: fmt.Sprintf("phone number %s is blocked", payout.PhoneNumber),
+		}, nil
+	}
+	return nil, nil
+}
+
+// CommandMaxRule caps a single payment per CommandID; CommandIDs not listed
+// are not limited
+type CommandMaxRule struct {
+	Max map[string]int
+}
+
+func (r CommandMaxRule) Evaluate(ctx context.Context, payout *Payout) (*PayoutViolation, error) {
+	limit, ok := r.Max[payout.CommandID]
+	if ok && payout.Amount > limit {
+		return &PayoutViolation{
+			Rule:   RuleCommandMax,
+			Reason: fmt.Sprintf("amount %d is above the %s maximum of %d", payout.Amount, payout.CommandID, limit),
+			Limit:  limit,
+		}, nil
+	}
+	return nil, nil
+}
+
+// RecipientDailyCapRule caps what one phone number is paid (or, for STK,
+// asked to pay) over the last 24 hours
+type RecipientDailyCapRule struct {
+	Cap  int
+	Repo storage.TransactionRepository
+}
+
+func (r RecipientDailyCapRule) Evaluate(ctx context.Context, payout *Payout) (*PayoutViolation, error) {
+	return capWindow(ctx, r.Repo, payout, r.Cap, 24*time.Hour, RuleRecipientDailyCap, storage.TransactionFilter{
+		PhoneNumber: payout.PhoneNumber,
+	}, "phone number "+payout.PhoneNumber, "24 hours")
+}
+
+// ClientHourlyCapRule caps what one API client moves over the last hour
+type ClientHourlyCapRule struct {
+	Cap  int
+	Repo storage.TransactionRepository
+}
+
+func (r ClientHourlyCapRule) Evaluate(ctx context.Context, payout *Payout) (*PayoutViolation, error) {
+	if payout.ClientID == "" {
+		return nil, nil
+	}
+	return capWindow(ctx, r.Repo, payout, r.Cap, time.Hour, RuleClientHourlyCap, storage.TransactionFilter{
+		ClientID: payout.ClientID,
+	}, "API client "+payout.ClientID, "hour")
+}
+
+// capWindow refuses payout if it would take the committed total of the
+// transactions matching filter over window above limit
+func capWindow(ctx context.Context, repo storage.TransactionRepository, payout *Payout, limit int, window time.Duration,
+	rule string, filter storage.TransactionFilter, subject, period string) (*PayoutViolation, error) {
+	filter.Type = payout.Type
+	filter.Statuses = committedStatuses
+	filter.Since = time.Now().Add(-window)
+
+	totals, err := repo.Totals(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total %s: %w", rule, err)
+	}
+	used := totals.Amount
+	if payout.Held {
+		used -= payout.Amount
+	}
+	if used+payout.Amount > limit {
+		return &PayoutViolation{
+			Rule:   rule,
+			Reason: fmt.Sprintf("%s would exceed its limit of %d per %s", subject, limit, period),
+			Limit:  limit,
+			Used:   used,
+		}, nil
+	}
+	return nil, nil
+}
+
+// PayoutRules evaluates a set of rules together. Caps are totalled from the
+// stored transactions, so a caller must hold Lock from Evaluate until the
+// payout is stored; otherwise payouts racing each other overshoot a cap.
+type PayoutRules struct {
+	rules []PayoutRule
+	locks storage.Locker
+}
+
+func NewPayoutRules(locks storage.Locker, rules ...PayoutRule) *PayoutRules {
+	return &PayoutRules{rules: rules, locks: locks}
+}
+
+// Lock serialises payouts of one type to the same phone number or by the same
+// client, across instances when the locker is shared. The returned func
+// releases the locks.
+func (r *PayoutRules) Lock(ctx context.Context, payout *Payout) (func(), error) {
+	names := []string{fmt.Sprintf("payout:%s:phone:%s", payout.Type, payout.PhoneNumber)}
+	if payout.ClientID != "" {
+		names = append(names, fmt.Sprintf("payout:%s:client:%s", payout.Type, payout.ClientID))
+	}
+
+	var releases []func()
+	release := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+
+	deadline := time.Now().Add(payoutLockWait)
+	for _, name := range names {
+		for {
+			unlock, acquired, err := r.locks.TryLock(ctx, name, payoutLockTTL)
+			if err != nil {
+				release()
+				return nil, fmt.Errorf("failed to lock %s: %w", name, err)
+			}
+			if acquired {
+				releases = append(releases, unlock)
+				break
+			}
+			if time.Now().After(deadline) {
+				release()
+				return nil, ErrPayoutBusy
+			}
+
+			select {
+			case <-ctx.Done():
+				release()
+				return nil, ctx.Err()
+			case <-time.After(payoutLockPoll):
+			}
+		}
+	}
+	return release, nil
+}
+
+// Evaluate runs every rule and returns all the violations, so a caller can
+// report them at once
+func (r *PayoutRules) Evaluate(ctx context.Context, payout *Payout) ([]PayoutViolation, error) {
+	var violations []PayoutViolation
+	for _, rule := range r.rules {
+		violation, err := rule.Evaluate(ctx, payout)
+		if err != nil {
+			return nil, err
+		}
+		if violation != nil {
+			violations = append(violations, *violation)
+		}
+	}
+	return violations, nil
+}
+
+// PayoutRulesFromConfig builds the rules enabled in limits; name prefixes
+// errors, e.g. "B2C"
+func PayoutRulesFromConfig(name string, limits config.PayoutLimits, repo storage.TransactionRepository, locks storage.Locker) (*PayoutRules, error) {
+	var rules []PayoutRule
+
+	if len(limits.BlockedMSISDNs) > 0 {
+		numbers := make(map[string]bool, len(limits.BlockedMSISDNs))
+		for _, msisdn := range limits.BlockedMSISDNs {
+			formatted, err := utils.FormatPhoneNumber(msisdn)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s_BLOCKED_MSISDNS entry %q: %w", name, msisdn, err)
+			}
+			numbers[formatted] = true
+		}
+		rules = append(rules, BlockedMSISDNRule{Numbers: numbers})
+	}
+
+	if len(limits.CommandMax) > 0 {
+		max := make(map[string]int, len(limits.CommandMax))
+		for _, entry := range limits.CommandMax {
+			commandID, raw, ok := strings.Cut(entry, "=")
+			amount, err := strconv.Atoi(strings.TrimSpace(raw))
+			if !ok || err != nil || amount <= 0 {
+				return nil, fmt.Errorf("invalid %s_COMMAND_MAX entry %q, want CommandID=amount", name, entry)
+			}
+			max[strings.TrimSpace(commandID)] = amount
+		}
+		rules = append(rules, CommandMaxRule{Max: max})
+	}
+
+	if limits.RecipientDailyCap > 0 {
+		rules = append(rules, RecipientDailyCapRule{Cap: limits.RecipientDailyCap, Repo: repo})
+	}
+	if limits.ClientHourlyCap > 0 {
+		rules = append(rules, ClientHourlyCapRule{Cap: limits.ClientHourlyCap, Repo: repo})
+	}
+
+	return NewPayoutRules(locks, rules...), nil
+}
@@ -0,0 +1,160 @@
+// ==========================
+// internal/services/payout_rules_test.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// storePayout stores a B2C payout to 254708374149 by client-a
+func storePayout(t *testing.T, repo storage.TransactionRepository, id string, amount int, status models.TransactionStatus) {
+	t.Helper()
+	tx := &models.Transaction{ID: id, Type: models.TransactionTypeB2C, PhoneNumber: "254708374149",
+		Amount: amount, ClientID: "client-a", Status: status}
+	if err := repo.Create(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func evaluate(t *testing.T, rules *PayoutRules, payout Payout) []string {
+	t.Helper()
+	payout.Type = models.TransactionTypeB2C
+	violations, err := rules.Evaluate(context.Background(), &payout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, violation := range violations {
+		got = append(got, violation.Rule)
+	}
+	return got
+}
+
+func TestPayoutRulesFromConfig(t *testing.T) {
+	repo := storage.NewMemoryRepository()
+	storePayout(t, repo, "tx-1", 4000, models.TransactionStatusSuccess)
+	limits := config.PayoutLimits{
+		RecipientDailyCap: 5000,
+		ClientHourlyCap:   8000,
+		CommandMax:        []string{"BusinessPayment=2000", " SalaryPayment = 10000"},
+		BlockedMSISDNs:    []string{"0722000000"},
+	}
+
+	rules, err := PayoutRulesFromConfig("B2C", limits, repo, storage.NewMemoryLocker())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := Payout{PhoneNumber: "254708374149", Amount: 1000, CommandID: "BusinessPayment", ClientID: "client-a"}
+	if got := evaluate(t, rules, allowed); got != nil {
+		t.Errorf("payout within every limit violated %v", got)
+	}
+	refused := Payout{PhoneNumber: "254722000000", Amount: 9000, CommandID: "BusinessPayment", ClientID: "client-a"}
+	want := []string{RuleBlockedMSISDN, RuleCommandMax, RuleRecipientDailyCap, RuleClientHourlyCap}
+	if got := evaluate(t, rules, refused); !reflect.DeepEqual(got, want) {
+		t.Errorf("violated %v, want %v", got, want)
+	}
+	recipient := Payout{PhoneNumber: "254708374149", Amount: 1001, CommandID: "SalaryPayment"}
+	if got := evaluate(t, rules, recipient); !reflect.DeepEqual(got, []string{RuleRecipientDailyCap}) {
+		t.Errorf("violated %v, want [%s]", got, RuleRecipientDailyCap)
+	}
+}
+
+func TestPayoutRulesFromConfigInvalid(t *testing.T) {
+	for _, limits := range []config.PayoutLimits{
+		{BlockedMSISDNs: []string{"12345"}},
+		{CommandMax: []string{"BusinessPayment"}},
+		{CommandMax: []string{"BusinessPayment=lots"}},
+		{CommandMax: []string{"BusinessPayment=0"}},
+	} {
+		if _, err := PayoutRulesFromConfig("B2C", limits, storage.NewMemoryRepository(), storage.NewMemoryLocker()); err == nil {
+			t.Errorf("PayoutRulesFromConfig(%+v) succeeded, want an error", limits)
+		}
+	}
+}
+
+func TestCapWindowCountsCommittedPayouts(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemoryRepository()
+	storePayout(t, repo, "tx-1", 600, models.TransactionStatusSuccess)
+	storePayout(t, repo, "tx-2", 9000, models.TransactionStatusFailed)
+	filter := storage.TransactionFilter{PhoneNumber: "254708374149"}
+
+	atLimit := &Payout{Type: models.TransactionTypeB2C, PhoneNumber: "254708374149", Amount: 400}
+	if got, err := capWindow(ctx, repo, atLimit, 1000, 24*time.Hour, RuleRecipientDailyCap, filter, "phone number 254708374149", "24 hours"); err != nil || got != nil {
+		t.Errorf("capWindow() at the limit = %+v, %v; want nil", got, err)
+	}
+
+	above := &Payout{Type: models.TransactionTypeB2C, PhoneNumber: "254708374149", Amount: 401}
+	got, err := capWindow(ctx, repo, above, 1000, 24*time.Hour, RuleRecipientDailyCap, filter, "phone number 254708374149", "24 hours")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &PayoutViolation{
+		Rule:   RuleRecipientDailyCap,
+		Reason: "phone number 254708374149 would exceed its limit of 1000 per 24 hours",
+		Limit:  1000,
+		Used:   600,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("capWindow() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCapWindowCountsHeldPayoutOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemoryRepository()
+	storePayout(t, repo, "tx-1", 600, models.TransactionStatusSuccess)
+	storePayout(t, repo, "tx-2", 400, models.TransactionStatusAwaitingApproval)
+	filter := storage.TransactionFilter{PhoneNumber: "254708374149"}
+
+	// Approving tx-2 must not count its own amount against the cap again
+	held := &Payout{Type: models.TransactionTypeB2C, PhoneNumber: "254708374149", Amount: 400, Held: true}
+	got, err := capWindow(ctx, repo, held, 1000, 24*time.Hour, RuleRecipientDailyCap, filter, "phone number 254708374149", "24 hours")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("capWindow() = %+v, want the held payout allowed", got)
+	}
+}
+
+func TestPayoutRulesLock(t *testing.T) {
+	ctx := context.Background()
+	rules := NewPayoutRules(storage.NewMemoryLocker())
+	payout := &Payout{Type: models.TransactionTypeB2C, PhoneNumber: "254708374149", ClientID: "client-a"}
+
+	release, err := rules.Lock(ctx, payout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second payout to the same number waits for the first
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, err := rules.Lock(waitCtx, &Payout{Type: models.TransactionTypeB2C, PhoneNumber: "254708374149"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Lock() while held = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Other numbers and types are not held up
+	other, err := rules.Lock(ctx, &Payout{Type: models.TransactionTypeSTK, PhoneNumber: "254708374149", ClientID: "client-a"})
+	if err != nil {
+		t.Fatalf("Lock() for another type = %v", err)
+	}
+	other()
+
+	release()
+	again, err := rules.Lock(ctx, payout)
+	if err != nil {
+		t.Fatalf("Lock() after release = %v", err)
+	}
+	again()
+}
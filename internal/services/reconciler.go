@@ -0,0 +1,137 @@
+// ==========================
+// internal/services/reconciler.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	reconcileTick  = 5 * time.Second
+	reconcileBatch = 100 // Overdue pushes checked per tick
+)
+
+// STKReconciler queries Daraja for STK Pushes whose callback never arrived.
+// It works from the PENDING pushes in the repository, so it survives restarts
+// and can run on every instance: a lease per push, held until its next check
+// is due, keeps instances from querying the same push twice.
+type STKReconciler struct {
+	config     *config.Config
+	stkService *STKService
+	repo       storage.TransactionRepository
+	locks      storage.Locker
+}
+
+func NewSTKReconciler(cfg *config.Config, stkService *STKService, repo storage.TransactionRepository, locks storage.Locker) *STKReconciler {
+	return &STKReconciler{
+		config:     cfg,
+		stkService: stkService,
+		repo:       repo,
+		locks:      locks,
+	}
+}
+
+// Run polls overdue pushes until they reach a final state and hands each
+// resolved outcome to onResolved, which must ignore pushes already settled
+func (r *STKReconciler) Run(onResolved func(models.STKCallback)) {
+	ticker := time.NewTicker(reconcileTick)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.reconcile(onResolved)
+	}
+}
+
+// schedule returns when a push is queried, as offsets from its creation:
+// STKReconcileAfter, then every backoff, doubling up to STKReconcileMaxBackoff
+func (r *STKReconciler) schedule() []time.Duration {
+	offsets := make([]time.Duration, 0, r.config.STKReconcileAttempts)
+	offset := time.Duration(r.config.STKReconcileAfter) * time.Second
+	backoff := time.Duration(r.config.STKReconcileBackoff) * time.Second
+	maxBackoff := time.Duration(r.config.STKReconcileMaxBackoff) * time.Second
+
+	for len(offsets) < r.config.STKReconcileAttempts {
+		offsets = append(offsets, offset)
+		offset += backoff
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+	return offsets
+}
+
+func (r *STKReconciler) reconcile(onResolved func(models.STKCallback)) {
+	schedule := r.schedule()
+	if len(schedule) == 0 {
+		return
+	}
+
+	// A push is given up on one max backoff after its last check is due
+	now := time.Now()
+	horizon := schedule[len(schedule)-1] + time.Duration(r.config.STKReconcileMaxBackoff)*time.Second
+
+	ctx := context.Background()
+	overdue, err := r.repo.List(ctx, storage.TransactionFilter{
+		Type:     models.TransactionTypeSTK,
+		Statuses: []models.TransactionStatus{models.TransactionStatusPending},
+		Since:    now.Add(-horizon),
+		Before:   now.Add(-schedule[0]),
+		Limit:    reconcileBatch,
+	})
+	if err != nil {
+		log.Printf("Failed to list pending STK pushes to reconcile: %v", err)
+		return
+	}
+
+	for _, tx := range overdue {
+		if tx.CheckoutRequestID == "" {
+			continue // Daraja never accepted it
+		}
+
+		attempt, next := dueCheck(schedule, horizon, now.Sub(tx.CreatedAt))
+		// The lease is left to expire when the next check is due
+		_, leased, err := r.locks.TryLock(ctx, "stk:reconcile:"+tx.ID, next)
+		if err != nil {
+			log.Printf("Failed to lease STK %s for reconciliation: %v", tx.CheckoutRequestID, err)
+			continue
+		}
+		if leased {
+			r.check(ctx, tx.CheckoutRequestID, attempt == len(schedule)-1, onResolved)
+		}
+	}
+}
+
+// dueCheck returns the latest check due for a push of the given age, and how
+// long until the one after it
+func dueCheck(schedule []time.Duration, horizon, age time.Duration) (int, time.Duration) {
+	attempt := 0
+	for attempt+1 < len(schedule) && schedule[attempt+1] <= age {
+		attempt++
+	}
+	if attempt+1 < len(schedule) {
+		return attempt, schedule[attempt+1] - age
+	}
+	return attempt, horizon - age
+}
+
+func (r *STKReconciler) check(ctx context.Context, checkoutRequestID string, last bool, onResolved func(models.STKCallback)) {
+	result, err := r.stkService.QueryStatus(ctx, checkoutRequestID)
+	if err != nil {
+		log.Printf("STK reconcile query failed for %s: %v", checkoutRequestID, err)
+	}
+
+	if err == nil && result.Status.IsFinal() {
+		log.Printf("STK %s reconciled without callback: %s", checkoutRequestID, result.Status)
+		onResolved(result.ToCallback())
+		return
+	}
+	if last {
+		log.Printf("STK %s still unresolved after %d queries, giving up", checkoutRequestID, r.config.STKReconcileAttempts)
+	}
+}
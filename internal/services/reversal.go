@@ -0,0 +1,59 @@
+// ==========================
+// internal/services/reversal.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/daraja"
+	"awesomeProject/internal/models"
+	"context"
+	"fmt"
+)
+
+type ReversalService struct {
+	config    *config.Config
+	client    *daraja.Client
+	callbacks *CallbackTokens
+}
+
+func NewReversalService(cfg *config.Config, client *daraja.Client, callbacks *CallbackTokens) *ReversalService {
+	return &ReversalService{
+		config:    cfg,
+		client:    client,
+		callbacks: callbacks,
+	}
+}
+
+// Reverse asks Daraja to reverse a payment received on our shortcode. The
+// outcome arrives asynchronously on ReversalResultURL, tokenised for ref.
+func (s *ReversalService) Reverse(ctx context.Context, transactionID string, amount int, remarks, occasion, ref string) (*models.ReversalResponse, error) {
+	credential, err := securityCredential(s.config)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.client.Reversal(ctx, &daraja.ReversalRequest{
+		Initiator:              s.config.InitiatorName,
+		SecurityCredential:     credential,
+		CommandID:              "TransactionReversal",
+		TransactionID:          transactionID,
+		Amount:                 amount,
+		ReceiverParty:          fmt.Sprintf("%d", s.config.BusinessShortCode),
+		ReceiverIdentifierType: daraja.IdentifierTypeOrganization,
+		ResultURL:              s.callbacks.URL(s.config.ReversalResultURL, ref),
+		QueueTimeOutURL:        s.callbacks.URL(s.config.ReversalTimeoutURL, ref),
+		Remarks:                remarks,
+		Occasion:               occasion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ReversalResponse{
+		ConversationID:           result.ConversationID,
+		OriginatorConversationID: result.OriginatorConversationID,
+		ResponseCode:             result.ResponseCode,
+		ResponseDescription:      result.ResponseDescription,
+	}, nil
+}
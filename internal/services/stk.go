@@ -0,0 +1,119 @@
+// ==========================
+// internal/services/stk.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/daraja"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// STKTransactionType is the Daraja TransactionType of every STK Push we send
+const STKTransactionType = "CustomerPayBillOnline"
+
+type STKService struct {
+	config    *config.Config
+	client    *daraja.Client
+	callbacks *CallbackTokens
+}
+
+func NewSTKService(cfg *config.Config, client *daraja.Client, callbacks *CallbackTokens) *STKService {
+	return &STKService{
+		config:    cfg,
+		client:    client,
+		callbacks: callbacks,
+	}
+}
+
+// password signs STK requests with the shortcode's passkey
+func (s *STKService) password() (password, timestamp string) {
+	timestamp = utils.GetTimestamp()
+	password = utils.GeneratePassword(
+		strconv.Itoa(s.config.BusinessShortCode),
+		s.config.Passkey,
+		timestamp,
+	)
+	return password, timestamp
+}
+
+// InitiatePush sends an STK Push prompt to phoneNumber, which must already be
+// formatted as 254XXXXXXXXX. ref is the ID the transaction will be stored
+// under; the callback URL is only valid for it.
+func (s *STKService) InitiatePush(ctx context.Context, req *models.STKPushRequest, phoneNumber, ref string) (*models.STKPushResponse, error) {
+	password, timestamp := s.password()
+	shortCode := strconv.Itoa(s.config.BusinessShortCode)
+
+	resp, err := s.client.STKPush(ctx, &daraja.STKPushRequest{
+		BusinessShortCode: shortCode,
+		Password:          password,
+		Timestamp:         timestamp,
+		TransactionType:   STKTransactionType,
+		Amount:            req.Amount,
+		PartyA:            phoneNumber,
+		PartyB:            shortCode,
+		PhoneNumber:       phoneNumber,
+		CallBackURL:       s.callbacks.URL(s.config.STKCallbackURL, ref),
+		AccountReference:  req.AccountReference,
+		TransactionDesc:   req.TransactionDesc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.STKPushResponse{
+		MerchantRequestID:   resp.MerchantRequestID,
+		CheckoutRequestID:   resp.CheckoutRequestID,
+		ResponseCode:        resp.ResponseCode,
+		ResponseDescription: resp.ResponseDescription,
+		CustomerMessage:     resp.CustomerMessage,
+	}, nil
+}
+
+// QueryStatus asks Daraja for the current state of an STK Push transaction
+func (s *STKService) QueryStatus(ctx context.Context, checkoutRequestID string) (*models.STKStatusResult, error) {
+	password, timestamp := s.password()
+
+	result, err := s.client.STKQuery(ctx, &daraja.STKQueryRequest{
+		BusinessShortCode: strconv.Itoa(s.config.BusinessShortCode),
+		Password:          password,
+		Timestamp:         timestamp,
+		CheckoutRequestID: checkoutRequestID,
+	})
+
+	// Daraja answers with an error while the customer has not yet acted on the prompt
+	var apiErr *daraja.APIError
+	if errors.As(err, &apiErr) && isSTKQueryPending(apiErr) {
+		return &models.STKStatusResult{
+			CheckoutRequestID: checkoutRequestID,
+			Status:            models.STKStatusPending,
+			ResultDesc:        apiErr.ErrorMessage,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resultCode, err := strconv.Atoi(result.ResultCode.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid ResultCode %q: %w", result.ResultCode, err)
+	}
+
+	return &models.STKStatusResult{
+		MerchantRequestID: result.MerchantRequestID,
+		CheckoutRequestID: result.CheckoutRequestID,
+		Status:            models.STKStatusFromResultCode(resultCode),
+		ResultCode:        resultCode,
+		ResultDesc:        result.ResultDesc,
+	}, nil
+}
+
+func isSTKQueryPending(apiErr *daraja.APIError) bool {
+	return apiErr.ErrorCode == "500.001.1001" && strings.Contains(strings.ToLower(apiErr.ErrorMessage), "being processed")
+}
@@ -0,0 +1,58 @@
+// ==========================
+// internal/services/transaction_status.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/daraja"
+	"awesomeProject/internal/models"
+	"context"
+	"fmt"
+)
+
+type TransactionStatusService struct {
+	config    *config.Config
+	client    *daraja.Client
+	callbacks *CallbackTokens
+}
+
+func NewTransactionStatusService(cfg *config.Config, client *daraja.Client, callbacks *CallbackTokens) *TransactionStatusService {
+	return &TransactionStatusService{
+		config:    cfg,
+		client:    client,
+		callbacks: callbacks,
+	}
+}
+
+// Query asks Daraja for the state of a transaction. The answer arrives
+// asynchronously on TransactionStatusResultURL, tokenised for ref.
+func (s *TransactionStatusService) Query(ctx context.Context, req *models.TransactionStatusRequest, ref string) (*models.TransactionStatusResponse, error) {
+	credential, err := securityCredential(s.config)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.client.TransactionStatus(ctx, &daraja.TransactionStatusRequest{
+		Initiator:              s.config.InitiatorName,
+		SecurityCredential:     credential,
+		CommandID:              "TransactionStatusQuery",
+		TransactionID:          req.TransactionID,
+		OriginalConversationID: req.OriginatorConversationID,
+		PartyA:                 fmt.Sprintf("%d", s.config.BusinessShortCode),
+		IdentifierType:         daraja.IdentifierTypeShortCode,
+		ResultURL:              s.callbacks.URL(s.config.TransactionStatusResultURL, ref),
+		QueueTimeOutURL:        s.callbacks.URL(s.config.TransactionStatusTimeoutURL, ref),
+		Remarks:                req.Remarks,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TransactionStatusResponse{
+		ConversationID:           result.ConversationID,
+		OriginatorConversationID: result.OriginatorConversationID,
+		ResponseCode:             result.ResponseCode,
+		ResponseDescription:      result.ResponseDescription,
+	}, nil
+}
@@ -0,0 +1,80 @@
+// ==========================
+// internal/services/webhook_targets.go
+// ==========================
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrUnsafeWebhookURL refuses webhook URLs that are not https or that point
+// into private networks, so endpoints cannot be used to reach internal hosts
+var ErrUnsafeWebhookURL = errors.New("webhook URL must be https and resolve to public addresses")
+
+// Shared address space used by carrier-grade NAT (RFC 6598)
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// publicIP reports whether ip is routable on the internet
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || sharedAddressSpace.Contains(ip))
+}
+
+// checkWebhookURL refuses raw unless it is an https URL whose host resolves
+// only to public addresses
+func checkWebhookURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("%w: %s", ErrUnsafeWebhookURL, raw)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: cannot resolve %s: %v", ErrUnsafeWebhookURL, u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrUnsafeWebhookURL, u.Hostname(), addr.IP)
+		}
+	}
+	return nil
+}
+
+// newWebhookClient sends webhooks. The address is checked again when
+// connecting, as DNS may have changed since the endpoint was registered, and
+// redirects are not followed.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("%w: refusing to connect to %s", ErrUnsafeWebhookURL, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
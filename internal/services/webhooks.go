@@ -0,0 +1,279 @@
+// ==========================
+// internal/services/webhooks.go
+// ==========================
+package services
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/storage"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Headers sent with every webhook. The signature is
+// "v1=" + hex(HMAC-SHA256(secret, timestamp + "." + body)); receivers should
+// recompute it and reject old timestamps to stop replays.
+const (
+	WebhookIDHeader        = "X-Webhook-Id" // Delivery id, the same on every retry
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Webhook-Timestamp" // Unix seconds
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	webhookPollInterval = 2 * time.Second
+	webhookBatchSize    = 20
+	webhookQueueTimeout = 5 * time.Second
+)
+
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// WebhookService queues events for partner endpoints and sends them, retrying
+// with exponential backoff until they are accepted or dead-lettered
+type WebhookService struct {
+	config *config.Config
+	store  storage.WebhookStore
+	client *http.Client
+}
+
+func NewWebhookService(cfg *config.Config, store storage.WebhookStore) *WebhookService {
+	return &WebhookService{
+		config: cfg,
+		store:  store,
+		client: newWebhookClient(time.Duration(cfg.WebhookTimeout) * time.Second),
+	}
+}
+
+// CreateEndpoint registers url for the given event types, generating a
+// signing secret if none is given
+func (s *WebhookService) CreateEndpoint(ctx context.Context, req models.WebhookEndpointRequest) (*models.WebhookEndpoint, error) {
+	if err := checkWebhookURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		ID:     uuid.New().String(),
+		URL:    req.URL,
+		Secret: req.Secret,
+	}
+	for _, t := range req.EventTypes {
+		eventType := models.EventType(t)
+		if !eventType.Valid() {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, t)
+		}
+		endpoint.EventTypes = append(endpoint.EventTypes, eventType)
+	}
+
+	if endpoint.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		endpoint.Secret = hex.EncodeToString(secret)
+	}
+
+	if err := s.store.CreateEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// Notify queues event for every endpoint subscribed to its type. Deliveries
+// are persisted before it returns, so they survive a restart.
+func (s *WebhookService) Notify(event models.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookQueueTimeout)
+	defer cancel()
+
+	endpoints, err := s.store.ListEndpoints(ctx)
+	if err != nil {
+		log.Printf("Failed to load webhook endpoints for %s event: %v", event.Type, err)
+		return
+	}
+
+	var payload []byte
+	for _, endpoint := range endpoints {
+		if !endpoint.Wants(event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				log.Printf("Failed to encode %s event for webhooks: %v", event.Type, err)
+				return
+			}
+		}
+
+		delivery := &models.WebhookDelivery{
+			ID:            uuid.New().String(),
+			EndpointID:    endpoint.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+		if err := s.store.CreateDelivery(ctx, delivery); err != nil {
+			log.Printf("Failed to queue %s event for webhook %s: %v", event.Type, endpoint.ID, err)
+		}
+	}
+}
+
+// Redeliver queues a delivery again with a fresh set of retries, whatever
+// its current status
+func (s *WebhookService) Redeliver(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	delivery, err := s.store.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := s.store.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Run sends due deliveries until the process exits
+func (s *WebhookService) Run() {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.sendDue()
+	}
+}
+
+func (s *WebhookService) sendDue() {
+	ctx := context.Background()
+	now := time.Now()
+
+	// Long enough for every claimed delivery to be attempted once
+	lease := now.Add(2 * s.client.Timeout)
+	deliveries, err := s.store.ClaimDue(ctx, now, lease, webhookBatchSize)
+	if err != nil {
+		log.Printf("Failed to claim webhook deliveries: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			s.attempt(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+func (s *WebhookService) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	started := time.Now()
+
+	var statusCode int
+	endpoint, err := s.store.GetEndpoint(ctx, delivery.EndpointID)
+	deleted := errors.Is(err, storage.ErrNotFound)
+	if deleted {
+		err = errors.New("endpoint was deleted")
+	} else if err == nil {
+		statusCode, err = s.send(ctx, endpoint, delivery)
+	}
+
+	attempt := models.WebhookAttempt{
+		At:         started,
+		StatusCode: statusCode,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	delivery.History = append(delivery.History, attempt)
+	delivery.Attempts++
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliveryDelivered
+	case deleted || delivery.Attempts >= s.config.WebhookMaxAttempts:
+		delivery.Status = models.WebhookDeliveryDead
+		log.Printf("Webhook delivery %s dead-lettered after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+	default:
+		delivery.NextAttemptAt = time.Now().Add(s.backoff(delivery.Attempts))
+		log.Printf("Webhook delivery %s failed (attempt %d), retrying at %s: %v",
+			delivery.ID, delivery.Attempts, delivery.NextAttemptAt.Format(time.RFC3339), err)
+	}
+
+	if err := s.store.UpdateDelivery(ctx, delivery); err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// send posts the delivery once and returns the status code the endpoint
+// answered with; anything but 2xx is an error
+func (s *WebhookService) send(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, error) {
+	// Endpoints registered before URLs were checked may still use http
+	if u, err := url.Parse(endpoint.URL); err != nil || u.Scheme != "https" {
+		return 0, fmt.Errorf("%w: %s", ErrUnsafeWebhookURL, endpoint.URL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, delivery.ID)
+	req.Header.Set(WebhookEventHeader, string(delivery.EventType))
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "v1="+signWebhook(endpoint.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff is the wait after the given number of failed attempts
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	wait := time.Duration(s.config.WebhookRetryBackoff) * time.Second
+	maxBackoff := time.Duration(s.config.WebhookMaxBackoff) * time.Second
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,195 @@
+// ==========================
+// internal/storage/api_clients.go
+// ==========================
+package storage
+
+import (
+	"awesomeProject/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIClientStore persists the clients allowed to call the REST API
+type APIClientStore interface {
+	Create(ctx context.Context, client *models.APIClient) error
+	GetByID(ctx context.Context, id string) (*models.APIClient, error)
+	GetByKeyHash(ctx context.Context, keyHash string) (*models.APIClient, error)
+	List(ctx context.Context) ([]*models.APIClient, error)
+	// Revoke stamps RevokedAt on a client; its key stops working at once
+	Revoke(ctx context.Context, id string, at time.Time) error
+}
+
+type MemoryAPIClientStore struct {
+	clients map[string]*models.APIClient
+	mu      sync.Mutex
+}
+
+func NewMemoryAPIClientStore() *MemoryAPIClientStore {
+	return &MemoryAPIClientStore{clients: make(map[string]*models.APIClient)}
+}
+
+func (s *MemoryAPIClientStore) Create(ctx context.Context, client *models.APIClient) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client.CreatedAt = time.Now()
+	s.clients[client.ID] = copyAPIClient(client)
+	return nil
+}
+
+func (s *MemoryAPIClientStore) GetByID(ctx context.Context, id string) (*models.APIClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client, ok := s.clients[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyAPIClient(client), nil
+}
+
+func (s *MemoryAPIClientStore) GetByKeyHash(ctx context.Context, keyHash string) (*models.APIClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, client := range s.clients {
+		if client.KeyHash == keyHash {
+			return copyAPIClient(client), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryAPIClientStore) List(ctx context.Context) ([]*models.APIClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clients := make([]*models.APIClient, 0, len(s.clients))
+	for _, client := range s.clients {
+		clients = append(clients, copyAPIClient(client))
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].CreatedAt.Before(clients[j].CreatedAt) })
+	return clients, nil
+}
+
+func (s *MemoryAPIClientStore) Revoke(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client, ok := s.clients[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if client.RevokedAt == nil {
+		client.RevokedAt = &at
+	}
+	return nil
+}
+
+func copyAPIClient(client *models.APIClient) *models.APIClient {
+	c := *client
+	c.Scopes = append([]models.Scope(nil), client.Scopes...)
+	if client.RevokedAt != nil {
+		revokedAt := *client.RevokedAt
+		c.RevokedAt = &revokedAt
+	}
+	return &c
+}
+
+const apiClientColumns = `id, name, key_prefix, key_hash, scopes, created_by, created_at, revoked_at`
+
+type PostgresAPIClientStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAPIClientStore(db *sql.DB) *PostgresAPIClientStore {
+	return &PostgresAPIClientStore{db: db}
+}
+
+func (s *PostgresAPIClientStore) Create(ctx context.Context, client *models.APIClient) error {
+	scopes := make([]string, len(client.Scopes))
+	for i, scope := range client.Scopes {
+		scopes[i] = string(scope)
+	}
+	client.CreatedAt = time.Now()
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO api_clients (`+apiClientColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		client.ID, client.Name, client.KeyPrefix, client.KeyHash, pq.Array(scopes), client.CreatedBy, client.CreatedAt, client.RevokedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert API client: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresAPIClientStore) GetByID(ctx context.Context, id string) (*models.APIClient, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+apiClientColumns+` FROM api_clients WHERE id = $1`, id)
+	return scanAPIClient(row)
+}
+
+func (s *PostgresAPIClientStore) GetByKeyHash(ctx context.Context, keyHash string) (*models.APIClient, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+apiClientColumns+` FROM api_clients WHERE key_hash = $1`, keyHash)
+	return scanAPIClient(row)
+}
+
+func (s *PostgresAPIClientStore) List(ctx context.Context) ([]*models.APIClient, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+apiClientColumns+` FROM api_clients ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API clients: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []*models.APIClient
+	for rows.Next() {
+		client, err := scanAPIClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, rows.Err()
+}
+
+func (s *PostgresAPIClientStore) Revoke(ctx context.Context, id string, at time.Time) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE api_clients SET revoked_at = COALESCE(revoked_at, $2)
+		WHERE id = $1`, id, at)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API client: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanAPIClient(row rowScanner) (*models.APIClient, error) {
+	var (
+		client    models.APIClient
+		scopes    []string
+		revokedAt sql.NullTime
+	)
+
+	err := row.Scan(&client.ID, &client.Name, &client.KeyPrefix, &client.KeyHash, pq.Array(&scopes),
+		&client.CreatedBy, &client.CreatedAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan API client: %w", err)
+	}
+
+	for _, scope := range scopes {
+		client.Scopes = append(client.Scopes, models.Scope(scope))
+	}
+	if revokedAt.Valid {
+		client.RevokedAt = &revokedAt.Time
+	}
+	return &client, nil
+}
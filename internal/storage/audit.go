@@ -0,0 +1,114 @@
+// ==========================
+// internal/storage/audit.go
+// ==========================
+package storage
+
+import (
+	"awesomeProject/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AuditLog is an append-only record of decisions taken on transactions
+type AuditLog interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+	// List returns the entries about subjectID, oldest first
+	List(ctx context.Context, subjectID string) ([]*models.AuditEntry, error)
+}
+
+type MemoryAuditLog struct {
+	entries []*models.AuditEntry
+	mu      sync.Mutex
+}
+
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{}
+}
+
+func (l *MemoryAuditLog) Record(ctx context.Context, entry *models.AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.CreatedAt = time.Now()
+	l.entries = append(l.entries, copyAuditEntry(entry))
+	return nil
+}
+
+func (l *MemoryAuditLog) List(ctx context.Context, subjectID string) ([]*models.AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []*models.AuditEntry
+	for _, entry := range l.entries {
+		if entry.SubjectID == subjectID {
+			entries = append(entries, copyAuditEntry(entry))
+		}
+	}
+	return entries, nil
+}
+
+func copyAuditEntry(entry *models.AuditEntry) *models.AuditEntry {
+	c := *entry
+	if entry.Details != nil {
+		c.Details = make(map[string]interface{}, len(entry.Details))
+		for k, v := range entry.Details {
+			c.Details[k] = v
+		}
+	}
+	return &c
+}
+
+type PostgresAuditLog struct {
+	db *sql.DB
+}
+
+func NewPostgresAuditLog(db *sql.DB) *PostgresAuditLog {
+	return &PostgresAuditLog{db: db}
+}
+
+func (l *PostgresAuditLog) Record(ctx context.Context, entry *models.AuditEntry) error {
+	details, err := marshalMetadata(entry.Details)
+	if err != nil {
+		return err
+	}
+	entry.CreatedAt = time.Now()
+
+	_, err = l.db.ExecContext(ctx, `INSERT INTO audit_log (id, action, actor_id, subject_id, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		entry.ID, entry.Action, entry.ActorID, entry.SubjectID, details, entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+func (l *PostgresAuditLog) List(ctx context.Context, subjectID string) ([]*models.AuditEntry, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT id, action, actor_id, subject_id, details, created_at
+		FROM audit_log WHERE subject_id = $1 ORDER BY created_at`, subjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		var (
+			entry   models.AuditEntry
+			details []byte
+		)
+		if err := rows.Scan(&entry.ID, &entry.Action, &entry.ActorID, &entry.SubjectID, &details, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &entry.Details); err != nil {
+				return nil, fmt.Errorf("failed to parse audit details: %w", err)
+			}
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,82 @@
+// ==========================
+// internal/storage/balance.go
+// ==========================
+package storage
+
+import (
+	"awesomeProject/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BalanceStore keeps the latest account balance snapshot. With Redis it is
+// shared by every instance and survives restarts.
+type BalanceStore interface {
+	// Get returns the latest snapshot, or nil if none was stored
+	Get(ctx context.Context) (*models.BalanceSnapshot, error)
+	Set(ctx context.Context, snapshot *models.BalanceSnapshot) error
+}
+
+type MemoryBalanceStore struct {
+	snapshot *models.BalanceSnapshot
+	mu       sync.RWMutex
+}
+
+func NewMemoryBalanceStore() *MemoryBalanceStore {
+	return &MemoryBalanceStore{}
+}
+
+func (s *MemoryBalanceStore) Get(ctx context.Context) (*models.BalanceSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot, nil
+}
+
+func (s *MemoryBalanceStore) Set(ctx context.Context, snapshot *models.BalanceSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = snapshot
+	return nil
+}
+
+const redisBalanceKey = redisKeyPrefix + "balance:snapshot"
+
+type RedisBalanceStore struct {
+	client *redis.Client
+}
+
+func NewRedisBalanceStore(client *redis.Client) *RedisBalanceStore {
+	return &RedisBalanceStore{client: client}
+}
+
+func (s *RedisBalanceStore) Get(ctx context.Context) (*models.BalanceSnapshot, error) {
+	data, err := s.client.Get(ctx, redisBalanceKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read balance snapshot: %w", err)
+	}
+
+	var snapshot models.BalanceSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode balance snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+func (s *RedisBalanceStore) Set(ctx context.Context, snapshot *models.BalanceSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode balance snapshot: %w", err)
+	}
+	if err := s.client.Set(ctx, redisBalanceKey, data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to store balance snapshot: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// ==========================
+// internal/storage/idempotency.go
+// ==========================
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Keys older than this are forgotten and may be reused
+const idempotencyKeyTTL = 24 * time.Hour
+
+// How often the memory store drops expired keys
+const idempotencySweepInterval = time.Minute
+
+// IdempotencyRecord is the first request seen for an Idempotency-Key and,
+// once it finished, the response sent for it
+type IdempotencyRecord struct {
+	Scope        string
+	Key          string
+	RequestHash  string
+	StatusCode   int
+	ResponseBody []byte
+	Completed    bool
+	CreatedAt    time.Time
+}
+
+type IdempotencyStore interface {
+	// Reserve claims key for a request. If the key is already taken it returns
+	// the existing record and false.
+	Reserve(ctx context.Context, scope, key, requestHash string) (*IdempotencyRecord, bool, error)
+	// Complete stores the response to replay for later requests with the key
+	Complete(ctx context.Context, scope, key string, statusCode int, body []byte) error
+	// Release frees a key whose request did not complete, so it can be retried
+	Release(ctx context.Context, scope, key string) error
+}
+
+type MemoryIdempotencyStore struct {
+	records   map[string]*IdempotencyRecord
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[string]*IdempotencyRecord),
+	}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, scope, key, requestHash string) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep()
+
+	id := scope + "\x00" + key
+	if existing, ok := s.records[id]; ok && time.Since(existing.CreatedAt) < idempotencyKeyTTL {
+		c := *existing
+		return &c, false, nil
+	}
+
+	record := &IdempotencyRecord{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   time.Now(),
+	}
+	s.records[id] = record
+	c := *record
+	return &c, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, scope, key string, statusCode int, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[scope+"\x00"+key]
+	if !ok {
+		return ErrNotFound
+	}
+	record.StatusCode = statusCode
+	record.ResponseBody = body
+	record.Completed = true
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := scope + "\x00" + key
+	if record, ok := s.records[id]; ok && !record.Completed {
+		delete(s.records, id)
+	}
+	return nil
+}
+
+// sweep drops expired keys, at most once per idempotencySweepInterval; the
+// caller holds s.mu
+func (s *MemoryIdempotencyStore) sweep() {
+	if time.Since(s.lastSweep) < idempotencySweepInterval {
+		return
+	}
+	s.lastSweep = time.Now()
+
+	for id, record := range s.records {
+		if time.Since(record.CreatedAt) >= idempotencyKeyTTL {
+			delete(s.records, id)
+		}
+	}
+}
+
+type PostgresIdempotencyStore struct {
+	db *sql.DB
+}
+
+func NewPostgresIdempotencyStore(db *sql.DB) *PostgresIdempotencyStore {
+	return &PostgresIdempotencyStore{db: db}
+}
+
+func (s *PostgresIdempotencyStore) Reserve(ctx context.Context, scope, key, requestHash string) (*IdempotencyRecord, bool, error) {
+	now := time.Now()
+
+	// Expired keys are free to be claimed again
+	if _, err := s.db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND created_at < $3`,
+		scope, key, now.Add(-idempotencyKeyTTL)); err != nil {
+		return nil, false, fmt.Errorf("failed to expire idempotency key: %w", err)
+	}
+
+	res, err := s.db.ExecContext(ctx, `INSERT INTO idempotency_keys (scope, key, request_hash, created_at)
+		VALUES ($1, $2, $3, $4) ON CONFLICT (scope, key) DO NOTHING`,
+		scope, key, requestHash, now)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 1 {
+		return &IdempotencyRecord{Scope: scope, Key: key, RequestHash: requestHash, CreatedAt: now}, true, nil
+	}
+
+	record := IdempotencyRecord{Scope: scope, Key: key}
+	err = s.db.QueryRowContext(ctx, `SELECT request_hash, status_code, response_body, completed, created_at
+		FROM idempotency_keys WHERE scope = $1 AND key = $2`, scope, key).
+		Scan(&record.RequestHash, &record.StatusCode, &record.ResponseBody, &record.Completed, &record.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, fmt.Errorf("idempotency key %q disappeared while reserving", key)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+	return &record, false, nil
+}
+
+func (s *PostgresIdempotencyStore) Complete(ctx context.Context, scope, key string, statusCode int, body []byte) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE idempotency_keys
+		SET status_code = $3, response_body = $4, completed = TRUE
+		WHERE scope = $1 AND key = $2`,
+		scope, key, statusCode, body)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresIdempotencyStore) Release(ctx context.Context, scope, key string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND NOT completed`, scope, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
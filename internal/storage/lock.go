@@ -0,0 +1,97 @@
+// ==========================
+// internal/storage/lock.go
+// ==========================
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Locker hands out named locks. With Redis the lock is held across every
+// instance; locks expire after ttl so a crashed holder cannot block others.
+type Locker interface {
+	// TryLock takes the lock without waiting. It returns false if someone
+	// else holds it; otherwise the returned func releases it.
+	TryLock(ctx context.Context, name string, ttl time.Duration) (func(), bool, error)
+}
+
+type MemoryLocker struct {
+	held map[string]memoryLock
+	mu   sync.Mutex
+}
+
+type memoryLock struct {
+	owner  string
+	expiry time.Time
+}
+
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{
+		held: make(map[string]memoryLock),
+	}
+}
+
+func (l *MemoryLocker) TryLock(ctx context.Context, name string, ttl time.Duration) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if existing, ok := l.held[name]; ok && time.Now().Before(existing.expiry) {
+		return nil, false, nil
+	}
+
+	owner := uuid.New().String()
+	l.held[name] = memoryLock{owner: owner, expiry: time.Now().Add(ttl)}
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		// The lock may have expired and been taken by someone else
+		if l.held[name].owner == owner {
+			delete(l.held, name)
+		}
+	}, true, nil
+}
+
+// Deletes the lock only if it still belongs to the caller
+var redisUnlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type RedisLocker struct {
+	client *redis.Client
+}
+
+func NewRedisLocker(client *redis.Client) *RedisLocker {
+	return &RedisLocker{client: client}
+}
+
+func (l *RedisLocker) TryLock(ctx context.Context, name string, ttl time.Duration) (func(), bool, error) {
+	key := redisKeyPrefix + "lock:" + name
+	owner := uuid.New().String()
+
+	acquired, err := l.client.SetNX(ctx, key, owner, ttl).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to take lock %s: %w", name, err)
+	}
+	if !acquired {
+		return nil, false, nil
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := redisUnlockScript.Run(ctx, l.client, []string{key}, owner).Err(); err != nil {
+			log.Printf("Failed to release lock %s: %v", name, err)
+		}
+	}, true, nil
+}
@@ -0,0 +1,163 @@
+// ==========================
+// internal/storage/memory.go
+// ==========================
+package storage
+
+import (
+	"awesomeProject/internal/models"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryRepository keeps transactions in process memory. It is meant for
+// tests and local development without a database.
+type MemoryRepository struct {
+	transactions map[string]*models.Transaction
+	mu           sync.RWMutex
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		transactions: make(map[string]*models.Transaction),
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, tx *models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkUnique(tx); err != nil {
+		return err
+	}
+	now := time.Now()
+	tx.CreatedAt = now
+	tx.UpdatedAt = now
+	r.transactions[tx.ID] = copyTransaction(tx)
+	return nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, tx *models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.transactions[tx.ID]; !ok {
+		return ErrNotFound
+	}
+	if err := r.checkUnique(tx); err != nil {
+		return err
+	}
+	tx.UpdatedAt = time.Now()
+	r.transactions[tx.ID] = copyTransaction(tx)
+	return nil
+}
+
+func (r *MemoryRepository) UpdateStatusIf(ctx context.Context, id string, from, to models.TransactionStatus) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, ok := r.transactions[id]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if tx.Status != from {
+		return false, nil
+	}
+	tx.Status = to
+	tx.UpdatedAt = time.Now()
+	return true, nil
+}
+
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*models.Transaction, error) {
+	return r.find(id, func(tx *models.Transaction) string { return tx.ID })
+}
+
+func (r *MemoryRepository) GetByCheckoutRequestID(ctx context.Context, checkoutRequestID string) (*models.Transaction, error) {
+	return r.find(checkoutRequestID, func(tx *models.Transaction) string { return tx.CheckoutRequestID })
+}
+
+func (r *MemoryRepository) GetByOriginatorConversationID(ctx context.Context, originatorConversationID string) (*models.Transaction, error) {
+	return r.find(originatorConversationID, func(tx *models.Transaction) string { return tx.OriginatorConversationID })
+}
+
+func (r *MemoryRepository) GetByReceiptNumber(ctx context.Context, receiptNumber string) (*models.Transaction, error) {
+	return r.find(receiptNumber, func(tx *models.Transaction) string { return tx.ReceiptNumber })
+}
+
+func (r *MemoryRepository) List(ctx context.Context, filter TransactionFilter) ([]*models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var transactions []*models.Transaction
+	for _, tx := range r.transactions {
+		if filter.matches(tx) {
+			transactions = append(transactions, copyTransaction(tx))
+		}
+	}
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].CreatedAt.After(transactions[j].CreatedAt) })
+	if filter.Limit > 0 && len(transactions) > filter.Limit {
+		transactions = transactions[:filter.Limit]
+	}
+	return transactions, nil
+}
+
+func (r *MemoryRepository) Totals(ctx context.Context, filter TransactionFilter) (TransactionTotals, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var totals TransactionTotals
+	for _, tx := range r.transactions {
+		if filter.matches(tx) {
+			totals.Count++
+			totals.Amount += tx.Amount
+		}
+	}
+	return totals, nil
+}
+
+// checkUnique refuses a second transaction with tx's OriginatorConversationID,
+// as the unique index does in Postgres. The caller holds r.mu.
+func (r *MemoryRepository) checkUnique(tx *models.Transaction) error {
+	if tx.OriginatorConversationID == "" {
+		return nil
+	}
+	for id, other := range r.transactions {
+		if id != tx.ID && other.OriginatorConversationID == tx.OriginatorConversationID {
+			return fmt.Errorf("%w: originator_conversation_id %s", ErrDuplicate, tx.OriginatorConversationID)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) find(key string, field func(*models.Transaction) string) (*models.Transaction, error) {
+	if key == "" {
+		return nil, ErrNotFound
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, tx := range r.transactions {
+		if field(tx) == key {
+			return copyTransaction(tx), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func copyTransaction(tx *models.Transaction) *models.Transaction {
+	c := *tx
+	if tx.ResultCode != nil {
+		code := *tx.ResultCode
+		c.ResultCode = &code
+	}
+	if tx.Metadata != nil {
+		c.Metadata = make(map[string]interface{}, len(tx.Metadata))
+		for k, v := range tx.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	return &c
+}
@@ -0,0 +1,46 @@
+// ==========================
+// internal/storage/memory_test.go
+// ==========================
+package storage
+
+import (
+	"awesomeProject/internal/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryRepositoryRefusesDuplicateOriginatorConversationID(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	if err := repo.Create(ctx, &models.Transaction{ID: "tx-1", OriginatorConversationID: "AG_1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Create(ctx, &models.Transaction{ID: "tx-2", OriginatorConversationID: "AG_1"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Create() = %v, want %v", err, ErrDuplicate)
+	}
+
+	second := &models.Transaction{ID: "tx-2"}
+	if err := repo.Create(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	second.OriginatorConversationID = "AG_1"
+	if err := repo.Update(ctx, second); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Update() = %v, want %v", err, ErrDuplicate)
+	}
+}
+
+func TestMemoryRepositoryAllowsUpdatingOwnOriginatorConversationID(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	tx := &models.Transaction{ID: "tx-1", OriginatorConversationID: "AG_1"}
+	if err := repo.Create(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	tx.Status = models.TransactionStatusSuccess
+	if err := repo.Update(ctx, tx); err != nil {
+		t.Errorf("Update() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,301 @@
+// ==========================
+// internal/storage/migrate.go
+// ==========================
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Arbitrary key for pg_advisory_lock so concurrent migrate runs serialize
+const migrationLockKey = 727_001
+
+const createSchemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	checksum   TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL
+)`
+
+var (
+	ErrMigrationDrift     = errors.New("database schema has drifted from embedded migrations")
+	ErrPendingMigrations  = errors.New("database has pending migrations")
+	ErrMigrationsMisnamed = errors.New("migration file name must look like 0001_name.sql")
+)
+
+type MigrationState string
+
+const (
+	MigrationApplied  MigrationState = "applied"
+	MigrationPending  MigrationState = "pending"
+	MigrationModified MigrationState = "modified" // applied, but the embedded file changed since
+	MigrationUnknown  MigrationState = "unknown"  // applied, but not shipped in this binary
+)
+
+// Migration is a forward-only SQL file embedded from migrations/
+type Migration struct {
+	Version  int
+	Name     string
+	SQL      string
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	State     MigrationState
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db *sql.DB
+}
+
+func NewMigrator(db *sql.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+// LoadMigrations reads the embedded migrations ordered by version
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		versionPart, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(versionPart)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrMigrationsMisnamed, entry.Name())
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		sum := sha256.Sum256(content)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     name,
+			SQL:      string(content),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Apply runs every pending migration, each in its own transaction, and
+// returns the ones it applied. It refuses to run on a drifted schema.
+func (m *Migrator) Apply(ctx context.Context) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	if _, err := conn.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDrift(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	done := make([]Migration, 0)
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return done, fmt.Errorf("failed to begin migration %d: %w", migration.Version, err)
+		}
+		if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+			tx.Rollback()
+			return done, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`,
+			migration.Version, migration.Name, migration.Checksum, time.Now()); err != nil {
+			tx.Rollback()
+			return done, fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return done, fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Status lists embedded and applied migrations with their state. It only
+// reads: a database without schema_migrations has every migration pending.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up schema_migrations table: %w", err)
+	}
+
+	applied := make(map[int]appliedMigration)
+	if exists {
+		if applied, err = loadApplied(ctx, m.db); err != nil {
+			return nil, err
+		}
+	}
+	return migrationStatuses(migrations, applied), nil
+}
+
+// migrationStatuses compares the embedded migrations with the applied ones
+func migrationStatuses(migrations []Migration, applied map[int]appliedMigration) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name, State: MigrationPending}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.appliedAt
+			status.AppliedAt = &appliedAt
+			status.State = MigrationApplied
+			if record.checksum != migration.Checksum {
+				status.State = MigrationModified
+			}
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, record := range applied {
+		appliedAt := record.appliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   version,
+			Name:      record.name,
+			State:     MigrationUnknown,
+			AppliedAt: &appliedAt,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses
+}
+
+// Verify fails if the database is not exactly at the embedded migrations
+func (m *Migrator) Verify(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := make([]string, 0)
+	for _, status := range statuses {
+		switch status.State {
+		case MigrationModified:
+			return fmt.Errorf("%w: migration %d_%s was modified after it was applied",
+				ErrMigrationDrift, status.Version, status.Name)
+		case MigrationUnknown:
+			return fmt.Errorf("%w: migration %d_%s is applied but not known to this build",
+				ErrMigrationDrift, status.Version, status.Name)
+		case MigrationPending:
+			pending = append(pending, fmt.Sprintf("%d_%s", status.Version, status.Name))
+		}
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %s (run `migrate up`)", ErrPendingMigrations, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func loadApplied(ctx context.Context, q querier) (map[int]appliedMigration, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var (
+			version int
+			record  appliedMigration
+		)
+		if err := rows.Scan(&version, &record.name, &record.checksum, &record.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+func checkDrift(migrations []Migration, applied map[int]appliedMigration) error {
+	known := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, record := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: migration %d_%s is applied but not known to this build",
+				ErrMigrationDrift, version, record.name)
+		}
+		if migration.Checksum != record.checksum {
+			return fmt.Errorf("%w: migration %d_%s was modified after it was applied",
+				ErrMigrationDrift, version, migration.Name)
+		}
+	}
+	return nil
+}
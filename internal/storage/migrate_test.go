@@ -0,0 +1,75 @@
+// ==========================
+// internal/storage/migrate_test.go
+// ==========================
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create_transactions", Checksum: "aaa"},
+	{Version: 2, Name: "add_index", Checksum: "bbb"},
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %d_%s is at position %d; versions must run from 1 without gaps", migration.Version, migration.Name, i)
+		}
+	}
+}
+
+func TestCheckDriftAcceptsAppliedMigrations(t *testing.T) {
+	applied := map[int]appliedMigration{
+		1: {name: "create_transactions", checksum: "aaa"},
+	}
+	if err := checkDrift(testMigrations, applied); err != nil {
+		t.Errorf("checkDrift() = %v, want nil", err)
+	}
+}
+
+func TestCheckDriftRejectsModifiedMigration(t *testing.T) {
+	applied := map[int]appliedMigration{
+		1: {name: "create_transactions", checksum: "changed"},
+	}
+	if err := checkDrift(testMigrations, applied); !errors.Is(err, ErrMigrationDrift) {
+		t.Errorf("checkDrift() = %v, want %v", err, ErrMigrationDrift)
+	}
+}
+
+func TestCheckDriftRejectsUnknownMigration(t *testing.T) {
+	applied := map[int]appliedMigration{
+		3: {name: "add_column", checksum: "ccc"},
+	}
+	if err := checkDrift(testMigrations, applied); !errors.Is(err, ErrMigrationDrift) {
+		t.Errorf("checkDrift() = %v, want %v", err, ErrMigrationDrift)
+	}
+}
+
+func TestMigrationStatuses(t *testing.T) {
+	appliedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	statuses := migrationStatuses(testMigrations, map[int]appliedMigration{
+		1: {name: "create_transactions", checksum: "changed", appliedAt: appliedAt},
+		3: {name: "add_column", checksum: "ccc", appliedAt: appliedAt},
+	})
+
+	want := []MigrationState{MigrationModified, MigrationPending, MigrationUnknown}
+	if len(statuses) != len(want) {
+		t.Fatalf("migrationStatuses() returned %d statuses, want %d", len(statuses), len(want))
+	}
+	for i, status := range statuses {
+		if status.Version != i+1 || status.State != want[i] {
+			t.Errorf("status %d = %d %s, want %d %s", i, status.Version, status.State, i+1, want[i])
+		}
+	}
+	if statuses[1].AppliedAt != nil {
+		t.Error("pending migration has an applied_at")
+	}
+}
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS transactions (
+	id                         TEXT PRIMARY KEY,
+	type                       TEXT NOT NULL,
+	status                     TEXT NOT NULL,
+	phone_number               TEXT NOT NULL DEFAULT '',
+	amount                     INTEGER NOT NULL DEFAULT 0,
+	account_reference          TEXT NOT NULL DEFAULT '',
+	command_id                 TEXT NOT NULL DEFAULT '',
+	merchant_request_id        TEXT NOT NULL DEFAULT '',
+	checkout_request_id        TEXT NOT NULL DEFAULT '',
+	conversation_id            TEXT NOT NULL DEFAULT '',
+	originator_conversation_id TEXT NOT NULL DEFAULT '',
+	receipt_number             TEXT NOT NULL DEFAULT '',
+	result_code                INTEGER,
+	result_desc                TEXT NOT NULL DEFAULT '',
+	metadata                   JSONB,
+	created_at                 TIMESTAMPTZ NOT NULL,
+	updated_at                 TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS transactions_checkout_request_id_idx ON transactions (checkout_request_id);
+CREATE INDEX IF NOT EXISTS transactions_originator_conversation_id_idx ON transactions (originator_conversation_id);
+CREATE INDEX IF NOT EXISTS transactions_receipt_number_idx ON transactions (receipt_number);
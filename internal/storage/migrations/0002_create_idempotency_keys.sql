@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	scope         TEXT NOT NULL,
+	key           TEXT NOT NULL,
+	request_hash  TEXT NOT NULL,
+	status_code   INTEGER NOT NULL DEFAULT 0,
+	response_body BYTEA,
+	completed     BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at    TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (scope, key)
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON idempotency_keys (created_at);
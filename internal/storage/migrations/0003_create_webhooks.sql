@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+	id          TEXT PRIMARY KEY,
+	url         TEXT NOT NULL,
+	event_types TEXT[] NOT NULL,
+	secret      TEXT NOT NULL,
+	created_at  TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id              TEXT PRIMARY KEY,
+	endpoint_id     TEXT NOT NULL,
+	event_id        TEXT NOT NULL,
+	event_type      TEXT NOT NULL,
+	payload         JSONB NOT NULL,
+	status          TEXT NOT NULL,
+	attempts        INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMPTZ NOT NULL,
+	history         JSONB,
+	created_at      TIMESTAMPTZ NOT NULL,
+	updated_at      TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_id_idx ON webhook_deliveries (endpoint_id, created_at);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS api_clients (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	key_prefix TEXT NOT NULL,
+	key_hash   TEXT NOT NULL UNIQUE,
+	scopes     TEXT[] NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	revoked_at TIMESTAMPTZ
+);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS client_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS transactions_client_id_idx ON transactions (client_id);
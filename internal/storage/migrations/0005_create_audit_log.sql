@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+	id         TEXT PRIMARY KEY,
+	action     TEXT NOT NULL,
+	actor_id   TEXT NOT NULL,
+	subject_id TEXT NOT NULL,
+	details    JSONB,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_log_subject_id_idx ON audit_log (subject_id, created_at);
+CREATE INDEX IF NOT EXISTS transactions_phone_number_idx ON transactions (phone_number, created_at);
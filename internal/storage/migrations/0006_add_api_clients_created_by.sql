@@ -0,0 +1 @@
+ALTER TABLE api_clients ADD COLUMN IF NOT EXISTS created_by TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS transactions_type_status_idx ON transactions (type, status, created_at);
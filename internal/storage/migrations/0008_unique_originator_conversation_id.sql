@@ -0,0 +1,5 @@
+-- Callbacks are matched on originator_conversation_id, so two transactions
+-- must never share one. Empty means not assigned yet.
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_originator_conversation_id_key
+	ON transactions (originator_conversation_id) WHERE originator_conversation_id <> '';
+DROP INDEX IF EXISTS transactions_originator_conversation_id_idx;
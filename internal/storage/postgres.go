@@ -0,0 +1,273 @@
+// ==========================
+// internal/storage/postgres.go
+// ==========================
+package storage
+
+import (
+	"awesomeProject/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const transactionColumns = `id, type, status, phone_number, amount, account_reference, command_id,
+	merchant_request_id, checkout_request_id, conversation_id, originator_conversation_id,
+	receipt_number, result_code, result_desc, metadata, client_id, created_at, updated_at`
+
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+// OpenPostgres opens and pings a PostgreSQL connection pool
+func OpenPostgres(databaseURL string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+func (r *PostgresRepository) Create(ctx context.Context, tx *models.Transaction) error {
+	metadata, err := marshalMetadata(tx.Metadata)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tx.CreatedAt = now
+	tx.UpdatedAt = now
+
+	_, err = r.db.ExecContext(ctx, `INSERT INTO transactions (`+transactionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`,
+		tx.ID, tx.Type, tx.Status, tx.PhoneNumber, tx.Amount, tx.AccountReference, tx.CommandID,
+		tx.MerchantRequestID, tx.CheckoutRequestID, tx.ConversationID, tx.OriginatorConversationID,
+		tx.ReceiptNumber, tx.ResultCode, tx.ResultDesc, metadata, tx.ClientID, tx.CreatedAt, tx.UpdatedAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: originator_conversation_id %s", ErrDuplicate, tx.OriginatorConversationID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction: %w", err)
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is Postgres refusing a duplicate key
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func (r *PostgresRepository) Update(ctx context.Context, tx *models.Transaction) error {
+	metadata, err := marshalMetadata(tx.Metadata)
+	if err != nil {
+		return err
+	}
+
+	tx.UpdatedAt = time.Now()
+
+	res, err := r.db.ExecContext(ctx, `UPDATE transactions SET
+		status = $2, phone_number = $3, amount = $4, account_reference = $5, command_id = $6,
+		merchant_request_id = $7, checkout_request_id = $8, conversation_id = $9,
+		originator_conversation_id = $10, receipt_number = $11, result_code = $12,
+		result_desc = $13, metadata = $14, updated_at = $15
+		WHERE id = $1`,
+		tx.ID, tx.Status, tx.PhoneNumber, tx.Amount, tx.AccountReference, tx.CommandID,
+		tx.MerchantRequestID, tx.CheckoutRequestID, tx.ConversationID,
+		tx.OriginatorConversationID, tx.ReceiptNumber, tx.ResultCode,
+		tx.ResultDesc, metadata, tx.UpdatedAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: originator_conversation_id %s", ErrDuplicate, tx.OriginatorConversationID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update transaction: %w", err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UpdateStatusIf(ctx context.Context, id string, from, to models.TransactionStatus) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE transactions SET status = $3, updated_at = $4 WHERE id = $1 AND status = $2`,
+		id, from, to, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to update transaction status: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update transaction status: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*models.Transaction, error) {
+	return r.findOne(ctx, "id", id)
+}
+
+func (r *PostgresRepository) GetByCheckoutRequestID(ctx context.Context, checkoutRequestID string) (*models.Transaction, error) {
+	return r.findOne(ctx, "checkout_request_id", checkoutRequestID)
+}
+
+func (r *PostgresRepository) GetByOriginatorConversationID(ctx context.Context, originatorConversationID string) (*models.Transaction, error) {
+	return r.findOne(ctx, "originator_conversation_id", originatorConversationID)
+}
+
+func (r *PostgresRepository) GetByReceiptNumber(ctx context.Context, receiptNumber string) (*models.Transaction, error) {
+	return r.findOne(ctx, "receipt_number", receiptNumber)
+}
+
+func (r *PostgresRepository) List(ctx context.Context, filter TransactionFilter) ([]*models.Transaction, error) {
+	where, args := filter.where()
+	query := `SELECT ` + transactionColumns + ` FROM transactions` + where + ` ORDER BY created_at DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, tx)
+	}
+	return transactions, rows.Err()
+}
+
+func (r *PostgresRepository) Totals(ctx context.Context, filter TransactionFilter) (TransactionTotals, error) {
+	where, args := filter.where()
+
+	var totals TransactionTotals
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM transactions`+where, args...).
+		Scan(&totals.Count, &totals.Amount)
+	if err != nil {
+		return TransactionTotals{}, fmt.Errorf("failed to total transactions: %w", err)
+	}
+	return totals, nil
+}
+
+// where renders the filter as a WHERE clause and its arguments
+func (f TransactionFilter) where() (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.Type != "" {
+		add("type = $%d", f.Type)
+	}
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, len(f.Statuses))
+		for i, status := range f.Statuses {
+			statuses[i] = string(status)
+		}
+		add("status = ANY($%d)", pq.Array(statuses))
+	}
+	if f.PhoneNumber != "" {
+		add("phone_number = $%d", f.PhoneNumber)
+	}
+	if f.ClientID != "" {
+		add("client_id = $%d", f.ClientID)
+	}
+	if !f.Since.IsZero() {
+		add("created_at >= $%d", f.Since)
+	}
+	if !f.Before.IsZero() {
+		add("created_at < $%d", f.Before)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(conditions, " AND "), args
+}
+
+// findOne looks a transaction up by column; column is always a literal from this file
+func (r *PostgresRepository) findOne(ctx context.Context, column, value string) (*models.Transaction, error) {
+	if value == "" {
+		return nil, ErrNotFound
+	}
+
+	row := r.db.QueryRowContext(ctx, `SELECT `+transactionColumns+` FROM transactions
+		WHERE `+column+` = $1 ORDER BY created_at DESC LIMIT 1`, value)
+	return scanTransaction(row)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row rowScanner) (*models.Transaction, error) {
+	var (
+		tx         models.Transaction
+		resultCode sql.NullInt64
+		metadata   []byte
+	)
+
+	err := row.Scan(&tx.ID, &tx.Type, &tx.Status, &tx.PhoneNumber, &tx.Amount, &tx.AccountReference,
+		&tx.CommandID, &tx.MerchantRequestID, &tx.CheckoutRequestID, &tx.ConversationID,
+		&tx.OriginatorConversationID, &tx.ReceiptNumber, &resultCode, &tx.ResultDesc, &metadata,
+		&tx.ClientID, &tx.CreatedAt, &tx.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan transaction: %w", err)
+	}
+
+	if resultCode.Valid {
+		code := int(resultCode.Int64)
+		tx.ResultCode = &code
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &tx.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction metadata: %w", err)
+		}
+	}
+	return &tx, nil
+}
+
+// marshalMetadata encodes metadata as a JSON string; lib/pq would send a
+// []byte as bytea, which PostgreSQL refuses for a JSONB column
+func marshalMetadata(metadata map[string]interface{}) (sql.NullString, error) {
+	if metadata == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to marshal transaction metadata: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
@@ -0,0 +1,35 @@
+// ==========================
+// internal/storage/redis.go
+// ==========================
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// All keys this service writes live under one prefix so the Redis instance
+// can be shared
+const redisKeyPrefix = "mpesa:"
+
+// OpenRedis connects to the Redis server at url, e.g. redis://:password@host:6379/0
+func OpenRedis(url string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to reach redis: %w", err)
+	}
+	return client, nil
+}
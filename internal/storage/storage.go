@@ -0,0 +1,165 @@
+// ==========================
+// internal/storage/storage.go
+// ==========================
+package storage
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate means another transaction has the same OriginatorConversationID
+	ErrDuplicate = errors.New("record already exists")
+)
+
+// TransactionRepository persists STK and B2C transactions across their lifecycle
+type TransactionRepository interface {
+	Create(ctx context.Context, tx *models.Transaction) error
+	Update(ctx context.Context, tx *models.Transaction) error
+	// UpdateStatusIf atomically moves a transaction from one status to another.
+	// It returns false if the transaction was not in the from status.
+	UpdateStatusIf(ctx context.Context, id string, from, to models.TransactionStatus) (bool, error)
+	GetByID(ctx context.Context, id string) (*models.Transaction, error)
+	GetByCheckoutRequestID(ctx context.Context, checkoutRequestID string) (*models.Transaction, error)
+	GetByOriginatorConversationID(ctx context.Context, originatorConversationID string) (*models.Transaction, error)
+	GetByReceiptNumber(ctx context.Context, receiptNumber string) (*models.Transaction, error)
+	// List returns matching transactions, newest first
+	List(ctx context.Context, filter TransactionFilter) ([]*models.Transaction, error)
+	// Totals counts matching transactions and sums their amounts
+	Totals(ctx context.Context, filter TransactionFilter) (TransactionTotals, error)
+}
+
+// TransactionFilter narrows List and Totals; empty fields match anything
+type TransactionFilter struct {
+	Type        models.TransactionType
+	Statuses    []models.TransactionStatus
+	PhoneNumber string
+	ClientID    string
+	Since       time.Time // Created at or after
+	Before      time.Time // Created before
+	Limit       int       // List only
+}
+
+func (f TransactionFilter) matches(tx *models.Transaction) bool {
+	if len(f.Statuses) > 0 {
+		found := false
+		for _, status := range f.Statuses {
+			found = found || tx.Status == status
+		}
+		if !found {
+			return false
+		}
+	}
+	return (f.Type == "" || tx.Type == f.Type) &&
+		(f.PhoneNumber == "" || tx.PhoneNumber == f.PhoneNumber) &&
+		(f.ClientID == "" || tx.ClientID == f.ClientID) &&
+		!tx.CreatedAt.Before(f.Since) &&
+		(f.Before.IsZero() || tx.CreatedAt.Before(f.Before))
+}
+
+type TransactionTotals struct {
+	Count  int `json:"count"`
+	Amount int `json:"amount"`
+}
+
+// Store groups the repositories backed by one database, plus the state
+// shared between instances through Redis
+type Store struct {
+	Transactions TransactionRepository
+	Idempotency  IdempotencyStore
+	Webhooks     WebhookStore
+	Audit        AuditLog
+	APIClients   APIClientStore
+	Tokens       TokenStore
+	Locks        Locker
+	Balances     BalanceStore
+	db           *sql.DB
+	redis        *redis.Client
+}
+
+// Open returns PostgreSQL-backed repositories when DatabaseURL is set and
+// in-memory ones otherwise. Tokens and locks live in Redis when RedisURL is
+// set and are local to this process otherwise.
+func Open(cfg *config.Config) (*Store, error) {
+	store := &Store{}
+	if err := store.openShared(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.DatabaseURL == "" {
+		log.Println("Warning: DATABASE_URL not set, transactions are kept in memory only")
+		store.Transactions = NewMemoryRepository()
+		store.Idempotency = NewMemoryIdempotencyStore()
+		store.Webhooks = NewMemoryWebhookStore()
+		store.Audit = NewMemoryAuditLog()
+		store.APIClients = NewMemoryAPIClientStore()
+		return store, nil
+	}
+
+	db, err := OpenPostgres(cfg.DatabaseURL)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	store.db = db
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Refuse to run against a schema this binary does not expect
+	if err := NewMigrator(db).Verify(ctx); err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	store.Transactions = NewPostgresRepository(db)
+	store.Idempotency = NewPostgresIdempotencyStore(db)
+	store.Webhooks = NewPostgresWebhookStore(db)
+	store.Audit = NewPostgresAuditLog(db)
+	store.APIClients = NewPostgresAPIClientStore(db)
+	return store, nil
+}
+
+func (s *Store) openShared(cfg *config.Config) error {
+	if cfg.RedisURL == "" {
+		s.Tokens = NewMemoryTokenStore()
+		s.Locks = NewMemoryLocker()
+		s.Balances = NewMemoryBalanceStore()
+		return nil
+	}
+
+	client, err := OpenRedis(cfg.RedisURL)
+	if err != nil {
+		return err
+	}
+	s.redis = client
+	s.Tokens = NewRedisTokenStore(client)
+	s.Locks = NewRedisLocker(client)
+	s.Balances = NewRedisBalanceStore(client)
+	return nil
+}
+
+// Redis returns the Redis connection, or nil when RedisURL is not set
+func (s *Store) Redis() *redis.Client {
+	return s.redis
+}
+
+func (s *Store) Close() error {
+	var errs []error
+	if s.redis != nil {
+		errs = append(errs, s.redis.Close())
+	}
+	if s.db != nil {
+		errs = append(errs, s.db.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,77 @@
+// ==========================
+// internal/storage/tokens.go
+// ==========================
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TokenStore caches the Daraja OAuth access token. With Redis it is shared by
+// every instance, so scaling out does not multiply OAuth requests.
+type TokenStore interface {
+	// Get returns the cached token, or false if there is none or it expired
+	Get(ctx context.Context) (string, bool, error)
+	Set(ctx context.Context, token string, ttl time.Duration) error
+}
+
+type MemoryTokenStore struct {
+	token  string
+	expiry time.Time
+	mu     sync.RWMutex
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{}
+}
+
+func (s *MemoryTokenStore) Get(ctx context.Context) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.token != "" && time.Now().Before(s.expiry) {
+		return s.token, true, nil
+	}
+	return "", false, nil
+}
+
+func (s *MemoryTokenStore) Set(ctx context.Context, token string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+	s.expiry = time.Now().Add(ttl)
+	return nil
+}
+
+const redisTokenKey = redisKeyPrefix + "daraja:access_token"
+
+type RedisTokenStore struct {
+	client *redis.Client
+}
+
+func NewRedisTokenStore(client *redis.Client) *RedisTokenStore {
+	return &RedisTokenStore{client: client}
+}
+
+func (s *RedisTokenStore) Get(ctx context.Context) (string, bool, error) {
+	token, err := s.client.Get(ctx, redisTokenKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read access token: %w", err)
+	}
+	return token, true, nil
+}
+
+func (s *RedisTokenStore) Set(ctx context.Context, token string, ttl time.Duration) error {
+	if err := s.client.Set(ctx, redisTokenKey, token, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store access token: %w", err)
+	}
+	return nil
+}
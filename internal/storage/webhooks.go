@@ -0,0 +1,420 @@
+// ==========================
+// internal/storage/webhooks.go
+// ==========================
+package storage
+
+import (
+	"awesomeProject/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WebhookStore persists webhook endpoints and the queue of deliveries to them
+type WebhookStore interface {
+	CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error
+	GetEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context) ([]*models.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, id string) error
+
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	// ListDeliveries returns the newest deliveries first
+	ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]*models.WebhookDelivery, error)
+	// ClaimDue returns up to limit pending deliveries whose next attempt is due
+	// and moves that attempt to leaseUntil, so no other instance sends them
+	// meanwhile. A delivery whose sender died is retried once the lease ends.
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDelivery, error)
+}
+
+// WebhookDeliveryFilter narrows ListDeliveries; empty fields match anything
+type WebhookDeliveryFilter struct {
+	EndpointID string
+	Status     models.WebhookDeliveryStatus
+	Limit      int
+}
+
+func (f WebhookDeliveryFilter) matches(d *models.WebhookDelivery) bool {
+	return (f.EndpointID == "" || d.EndpointID == f.EndpointID) &&
+		(f.Status == "" || d.Status == f.Status)
+}
+
+type MemoryWebhookStore struct {
+	endpoints  map[string]*models.WebhookEndpoint
+	deliveries map[string]*models.WebhookDelivery
+	mu         sync.Mutex
+}
+
+func NewMemoryWebhookStore() *MemoryWebhookStore {
+	return &MemoryWebhookStore{
+		endpoints:  make(map[string]*models.WebhookEndpoint),
+		deliveries: make(map[string]*models.WebhookDelivery),
+	}
+}
+
+func (s *MemoryWebhookStore) CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoint.CreatedAt = time.Now()
+	s.endpoints[endpoint.ID] = copyEndpoint(endpoint)
+	return nil
+}
+
+func (s *MemoryWebhookStore) GetEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoint, ok := s.endpoints[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyEndpoint(endpoint), nil
+}
+
+func (s *MemoryWebhookStore) ListEndpoints(ctx context.Context) ([]*models.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoints := make([]*models.WebhookEndpoint, 0, len(s.endpoints))
+	for _, endpoint := range s.endpoints {
+		endpoints = append(endpoints, copyEndpoint(endpoint))
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].CreatedAt.Before(endpoints[j].CreatedAt) })
+	return endpoints, nil
+}
+
+func (s *MemoryWebhookStore) DeleteEndpoint(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.endpoints[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.endpoints, id)
+	return nil
+}
+
+func (s *MemoryWebhookStore) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+	s.deliveries[delivery.ID] = copyDelivery(delivery)
+	return nil
+}
+
+func (s *MemoryWebhookStore) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; !ok {
+		return ErrNotFound
+	}
+	delivery.UpdatedAt = time.Now()
+	s.deliveries[delivery.ID] = copyDelivery(delivery)
+	return nil
+}
+
+func (s *MemoryWebhookStore) GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.deliveries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyDelivery(delivery), nil
+}
+
+func (s *MemoryWebhookStore) ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deliveries []*models.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if filter.matches(delivery) {
+			deliveries = append(deliveries, copyDelivery(delivery))
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt) })
+	if filter.Limit > 0 && len(deliveries) > filter.Limit {
+		deliveries = deliveries[:filter.Limit]
+	}
+	return deliveries, nil
+}
+
+func (s *MemoryWebhookStore) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*models.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.Status == models.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*models.WebhookDelivery, len(due))
+	for i, delivery := range due {
+		delivery.NextAttemptAt = leaseUntil
+		claimed[i] = copyDelivery(delivery)
+	}
+	return claimed, nil
+}
+
+func copyEndpoint(endpoint *models.WebhookEndpoint) *models.WebhookEndpoint {
+	c := *endpoint
+	c.EventTypes = append([]models.EventType(nil), endpoint.EventTypes...)
+	return &c
+}
+
+func copyDelivery(delivery *models.WebhookDelivery) *models.WebhookDelivery {
+	c := *delivery
+	c.Payload = append([]byte(nil), delivery.Payload...)
+	c.History = append([]models.WebhookAttempt(nil), delivery.History...)
+	return &c
+}
+
+const webhookDeliveryColumns = `id, endpoint_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, history, created_at, updated_at`
+
+type PostgresWebhookStore struct {
+	db *sql.DB
+}
+
+func NewPostgresWebhookStore(db *sql.DB) *PostgresWebhookStore {
+	return &PostgresWebhookStore{db: db}
+}
+
+func (s *PostgresWebhookStore) CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	eventTypes := make([]string, len(endpoint.EventTypes))
+	for i, t := range endpoint.EventTypes {
+		eventTypes[i] = string(t)
+	}
+	endpoint.CreatedAt = time.Now()
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO webhook_endpoints (id, url, event_types, secret, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		endpoint.ID, endpoint.URL, pq.Array(eventTypes), endpoint.Secret, endpoint.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresWebhookStore) GetEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT id, url, event_types, secret, created_at
+		FROM webhook_endpoints WHERE id = $1`, id)
+	return scanEndpoint(row)
+}
+
+func (s *PostgresWebhookStore) ListEndpoints(ctx context.Context) ([]*models.WebhookEndpoint, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, url, event_types, secret, created_at
+		FROM webhook_endpoints ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []*models.WebhookEndpoint
+	for rows.Next() {
+		endpoint, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+func (s *PostgresWebhookStore) DeleteEndpoint(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresWebhookStore) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	history, err := marshalHistory(delivery.History)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO webhook_deliveries (`+webhookDeliveryColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		delivery.ID, delivery.EndpointID, delivery.EventID, delivery.EventType, string(delivery.Payload),
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, history, delivery.CreatedAt, delivery.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresWebhookStore) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	history, err := marshalHistory(delivery.History)
+	if err != nil {
+		return err
+	}
+
+	delivery.UpdatedAt = time.Now()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries SET
+		status = $2, attempts = $3, next_attempt_at = $4, history = $5, updated_at = $6
+		WHERE id = $1`,
+		delivery.ID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, history, delivery.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresWebhookStore) GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id)
+	return scanDelivery(row)
+}
+
+func (s *PostgresWebhookStore) ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if filter.EndpointID != "" {
+		args = append(args, filter.EndpointID)
+		where = append(where, fmt.Sprintf("endpoint_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY created_at DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return scanDeliveries(rows)
+}
+
+func (s *PostgresWebhookStore) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = $3
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $1 AND next_attempt_at <= $2
+			ORDER BY next_attempt_at LIMIT $4
+			FOR UPDATE SKIP LOCKED)
+		RETURNING `+webhookDeliveryColumns,
+		models.WebhookDeliveryPending, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return scanDeliveries(rows)
+}
+
+func scanEndpoint(row rowScanner) (*models.WebhookEndpoint, error) {
+	var (
+		endpoint   models.WebhookEndpoint
+		eventTypes []string
+	)
+
+	err := row.Scan(&endpoint.ID, &endpoint.URL, pq.Array(&eventTypes), &endpoint.Secret, &endpoint.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan webhook endpoint: %w", err)
+	}
+
+	for _, t := range eventTypes {
+		endpoint.EventTypes = append(endpoint.EventTypes, models.EventType(t))
+	}
+	return &endpoint, nil
+}
+
+func scanDeliveries(rows *sql.Rows) ([]*models.WebhookDelivery, error) {
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var (
+		delivery models.WebhookDelivery
+		payload  []byte
+		history  []byte
+	)
+
+	err := row.Scan(&delivery.ID, &delivery.EndpointID, &delivery.EventID, &delivery.EventType, &payload,
+		&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &history,
+		&delivery.CreatedAt, &delivery.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+	}
+
+	delivery.Payload = payload
+	if len(history) > 0 {
+		if err := json.Unmarshal(history, &delivery.History); err != nil {
+			return nil, fmt.Errorf("failed to parse webhook delivery history: %w", err)
+		}
+	}
+	return &delivery, nil
+}
+
+// marshalHistory encodes attempts as a JSON string for the JSONB column, see
+// marshalMetadata
+func marshalHistory(history []models.WebhookAttempt) (sql.NullString, error) {
+	if history == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(history)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to marshal webhook delivery history: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
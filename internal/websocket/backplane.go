@@ -0,0 +1,120 @@
+// ==========================
+// internal/websocket/backplane.go
+// ==========================
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Backplane carries broadcasts between every instance running a Hub, so a
+// callback received by one machine reaches clients connected to any of them.
+type Backplane interface {
+	// Publish sends message to every subscriber, including this instance
+	Publish(ctx context.Context, message []byte) error
+	// Subscribe returns the messages published by any instance. The channel
+	// is closed when ctx is cancelled.
+	Subscribe(ctx context.Context) (<-chan []byte, error)
+}
+
+// MemoryBackplane delivers messages within this process only. It is used when
+// there is a single instance and in tests.
+type MemoryBackplane struct {
+	subscribers map[chan []byte]struct{}
+	mu          sync.RWMutex
+}
+
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{
+		subscribers: make(map[chan []byte]struct{}),
+	}
+}
+
+func (b *MemoryBackplane) Publish(ctx context.Context, message []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- message:
+		default:
+			log.Println("Warning: backplane subscriber full, message dropped")
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBackplane) Subscribe(ctx context.Context) (<-chan []byte, error) {
+	ch := make(chan []byte, 256)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		close(ch)
+		b.mu.Unlock()
+	}()
+	return ch, nil
+}
+
+const redisBackplaneChannel = "mpesa:ws:payments"
+
+// RedisBackplane fans messages out to every instance through Redis pub/sub
+type RedisBackplane struct {
+	client *redis.Client
+}
+
+func NewRedisBackplane(client *redis.Client) *RedisBackplane {
+	return &RedisBackplane{client: client}
+}
+
+func (b *RedisBackplane) Publish(ctx context.Context, message []byte) error {
+	if err := b.client.Publish(ctx, redisBackplaneChannel, message).Err(); err != nil {
+		return fmt.Errorf("failed to publish to backplane: %w", err)
+	}
+	return nil
+}
+
+func (b *RedisBackplane) Subscribe(ctx context.Context) (<-chan []byte, error) {
+	pubsub := b.client.Subscribe(ctx, redisBackplaneChannel)
+
+	// Wait for the subscription so nothing published after we return is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to backplane: %w", err)
+	}
+
+	ch := make(chan []byte, 256)
+	go func() {
+		defer close(ch)
+		defer pubsub.Close()
+
+		// go-redis reconnects and resubscribes on its own
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case ch <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
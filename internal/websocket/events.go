@@ -0,0 +1,149 @@
+// ==========================
+// internal/websocket/events.go
+// ==========================
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Event is a broadcast together with its sequence id
+type Event struct {
+	Seq     uint64
+	Message []byte
+}
+
+// EventLog numbers broadcasts and keeps the most recent ones so reconnecting
+// clients can catch up on what they missed
+type EventLog interface {
+	// Append assigns the next sequence id to message, stores it and returns
+	// the message with a "seq" field added
+	Append(ctx context.Context, message []byte) (Event, error)
+	// Since returns the retained events with a sequence id after seq, oldest first
+	Since(ctx context.Context, seq uint64) ([]Event, error)
+}
+
+// withSeq adds the sequence id to a JSON object
+func withSeq(message []byte, seq uint64) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(message, &fields); err != nil {
+		return nil, fmt.Errorf("broadcast is not a JSON object: %w", err)
+	}
+	fields["seq"] = json.RawMessage(strconv.FormatUint(seq, 10))
+	return json.Marshal(fields)
+}
+
+// seqOf reads the sequence id of a broadcast, 0 if it has none
+func seqOf(message []byte) uint64 {
+	var fields struct {
+		Seq uint64 `json:"seq"`
+	}
+	json.Unmarshal(message, &fields)
+	return fields.Seq
+}
+
+// MemoryEventLog keeps the last size events of this process in a ring buffer
+type MemoryEventLog struct {
+	events []Event
+	next   int // Index the next event is written to
+	seq    uint64
+	mu     sync.RWMutex
+}
+
+func NewMemoryEventLog(size int) *MemoryEventLog {
+	return &MemoryEventLog{
+		events: make([]Event, size),
+	}
+}
+
+func (l *MemoryEventLog) Append(ctx context.Context, message []byte) (Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	message, err := withSeq(message, l.seq+1)
+	if err != nil {
+		return Event{}, err
+	}
+
+	l.seq++
+	event := Event{Seq: l.seq, Message: message}
+	if len(l.events) > 0 {
+		l.events[l.next] = event
+		l.next = (l.next + 1) % len(l.events)
+	}
+	return event, nil
+}
+
+func (l *MemoryEventLog) Since(ctx context.Context, seq uint64) ([]Event, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var events []Event
+	// Walk from the oldest slot to the newest
+	for i := range l.events {
+		event := l.events[(l.next+i)%len(l.events)]
+		if event.Seq > seq {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+const (
+	redisEventSeqKey = "mpesa:ws:seq"
+	redisEventLogKey = "mpesa:ws:events"
+)
+
+// RedisEventLog numbers events across every instance and keeps the last size
+// of them in a sorted set, so they survive restarts
+type RedisEventLog struct {
+	client *redis.Client
+	size   int64
+}
+
+func NewRedisEventLog(client *redis.Client, size int) *RedisEventLog {
+	return &RedisEventLog{client: client, size: int64(size)}
+}
+
+func (l *RedisEventLog) Append(ctx context.Context, message []byte) (Event, error) {
+	seq, err := l.client.Incr(ctx, redisEventSeqKey).Uint64()
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to assign event sequence: %w", err)
+	}
+
+	message, err = withSeq(message, seq)
+	if err != nil {
+		return Event{}, err
+	}
+
+	pipe := l.client.TxPipeline()
+	pipe.ZAdd(ctx, redisEventLogKey, redis.Z{Score: float64(seq), Member: message})
+	pipe.ZRemRangeByRank(ctx, redisEventLogKey, 0, -l.size-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return Event{}, fmt.Errorf("failed to store event %d: %w", seq, err)
+	}
+	return Event{Seq: seq, Message: message}, nil
+}
+
+func (l *RedisEventLog) Since(ctx context.Context, seq uint64) ([]Event, error) {
+	results, err := l.client.ZRangeByScoreWithScores(ctx, redisEventLogKey, &redis.ZRangeBy{
+		Min: "(" + strconv.FormatUint(seq, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+
+	events := make([]Event, 0, len(results))
+	for _, z := range results {
+		member, _ := z.Member.(string)
+		events = append(events, Event{Seq: uint64(z.Score), Message: []byte(member)})
+	}
+	return events, nil
+}
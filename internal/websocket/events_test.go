@@ -0,0 +1,162 @@
+// ==========================
+// internal/websocket/events_test.go
+// ==========================
+package websocket
+
+import (
+	"awesomeProject/internal/models"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// appendEvents logs one event per checkout request id, numbered from 1
+func appendEvents(t *testing.T, log EventLog, checkoutRequestIDs ...string) [][]byte {
+	t.Helper()
+	var messages [][]byte
+	for _, id := range checkoutRequestIDs {
+		message, err := json.Marshal(models.NewEvent(models.EventSTKCallback,
+			models.EventCorrelation{CheckoutRequestID: id}, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		event, err := log.Append(context.Background(), message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, event.Message)
+	}
+	return messages
+}
+
+// resume resumes a client subscribed to ws_CO_1 from since and returns the
+// seqs it was sent and its resume reply
+func resume(t *testing.T, log EventLog, sendSize int, pending [][]byte, since uint64) ([]uint64, resumeReply) {
+	t.Helper()
+	client := &Client{
+		hub:           NewHub(NewMemoryBackplane(), log),
+		send:          make(chan interface{}, sendSize),
+		subscriptions: map[Topic]bool{{TopicCheckoutRequestID, "ws_CO_1"}: true},
+		pending:       pending,
+	}
+	client.Resume(since)
+	close(client.send)
+
+	var (
+		seqs  []uint64
+		reply *resumeReply
+	)
+	for message := range client.send {
+		switch message := message.(type) {
+		case json.RawMessage:
+			if reply != nil {
+				t.Errorf("event %d sent after the resume reply", seqOf(message))
+			}
+			seqs = append(seqs, seqOf(message))
+		case resumeReply:
+			if reply != nil {
+				t.Errorf("second resume reply %+v", message)
+			}
+			reply = &message
+		default:
+			t.Errorf("unexpected message %#v", message)
+		}
+	}
+	if reply == nil {
+		t.Fatal("no resume reply sent")
+	}
+	return seqs, *reply
+}
+
+func TestMemoryEventLogSince(t *testing.T) {
+	log := NewMemoryEventLog(10)
+	appendEvents(t, log, "ws_CO_1", "ws_CO_1", "ws_CO_1")
+
+	events, err := log.Since(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 || events[0].Seq != 2 || events[1].Seq != 3 {
+		t.Fatalf("Since(1) = %+v, want events 2 and 3", events)
+	}
+	if seqOf(events[0].Message) != 2 {
+		t.Errorf("event 2 carries seq %d", seqOf(events[0].Message))
+	}
+}
+
+func TestMemoryEventLogEvictsOldest(t *testing.T) {
+	log := NewMemoryEventLog(3)
+	appendEvents(t, log, "ws_CO_1", "ws_CO_1", "ws_CO_1", "ws_CO_1", "ws_CO_1", "ws_CO_1", "ws_CO_1")
+
+	events, err := log.Since(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []uint64
+	for _, event := range events {
+		got = append(got, event.Seq)
+	}
+	if !reflect.DeepEqual(got, []uint64{5, 6, 7}) {
+		t.Errorf("Since(0) = %v, want [5 6 7]", got)
+	}
+}
+
+func TestClientResumeSendsMissedEvents(t *testing.T) {
+	log := NewMemoryEventLog(10)
+	appendEvents(t, log, "ws_CO_1", "ws_CO_2", "ws_CO_1", "ws_CO_2")
+
+	seqs, reply := resume(t, log, 10, nil, 0)
+
+	if !reflect.DeepEqual(seqs, []uint64{1, 3}) {
+		t.Errorf("sent events %v, want the subscribed [1 3]", seqs)
+	}
+	if want := (resumeReply{Type: "resumed", LastSeq: 4}); reply != want {
+		t.Errorf("reply = %+v, want %+v", reply, want)
+	}
+}
+
+func TestClientResumeReportsGap(t *testing.T) {
+	log := NewMemoryEventLog(2)
+	appendEvents(t, log, "ws_CO_1", "ws_CO_1", "ws_CO_1", "ws_CO_1")
+
+	// Event 2 was evicted before the client came back
+	_, reply := resume(t, log, 10, nil, 1)
+	if !reply.Gap {
+		t.Errorf("reply = %+v, want a gap", reply)
+	}
+
+	// Event 3 is the oldest retained, so nothing was missed
+	_, reply = resume(t, log, 10, nil, 2)
+	if reply.Gap {
+		t.Errorf("reply = %+v, want no gap", reply)
+	}
+}
+
+func TestClientResumeTruncatesToQueue(t *testing.T) {
+	log := NewMemoryEventLog(10)
+	appendEvents(t, log, "ws_CO_1", "ws_CO_1", "ws_CO_1", "ws_CO_1")
+
+	seqs, reply := resume(t, log, 3, nil, 0)
+
+	if !reflect.DeepEqual(seqs, []uint64{1, 2}) {
+		t.Errorf("sent events %v, want [1 2]", seqs)
+	}
+	if want := (resumeReply{Type: "resumed", LastSeq: 2, Truncated: true}); reply != want {
+		t.Errorf("reply = %+v, want %+v", reply, want)
+	}
+}
+
+func TestClientResumeDropsReplayedBroadcasts(t *testing.T) {
+	log := NewMemoryEventLog(10)
+	messages := appendEvents(t, log, "ws_CO_1", "ws_CO_1", "ws_CO_1")
+
+	// Events 2 and 3 were broadcast live while the client was resuming
+	seqs, _ := resume(t, log, 10, messages[1:], 1)
+
+	if !reflect.DeepEqual(seqs, []uint64{2, 3}) {
+		t.Errorf("sent events %v, want each of [2 3] once", seqs)
+	}
+}
@@ -4,8 +4,13 @@
 package websocket
 
 import (
+	"awesomeProject/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -19,31 +24,76 @@ const (
 )
 
 type Client struct {
-	conn *websocket.Conn
-	send chan interface{}
-	hub  *Hub
+	conn          *websocket.Conn
+	send          chan interface{}
+	hub           *Hub
+	claims        *Claims // Topics this connection may subscribe to
+	subscriptions map[Topic]bool
+	replaying     bool     // Live broadcasts wait in pending while missed events are sent
+	pending       [][]byte // Bounded by the size of send
+	closed        bool     // Set by the hub, under its lock, once send is closed
+	mu            sync.RWMutex
 }
 
+// Bounds how long a broadcast may wait on the backplane
+const publishTimeout = 5 * time.Second
+
+// Events waiting for the OnBroadcast listeners, and how long a broadcaster
+// waits for room in the queue before the event is dropped
+const (
+	notifyQueueSize = 1024
+	notifyTimeout   = 2 * time.Second
+)
+
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan interface{}
+	broadcast  chan []byte // Delivered to local clients only
+	outbound   chan []byte // Waiting to be published to every instance
+	backplane  Backplane
+	events     EventLog
+	listeners  []func(models.Event) // Set up before any broadcast
+	notify     chan models.Event    // Waiting to be handed to listeners
+	dropped    atomic.Uint64        // Events the listeners never saw
 	Register   chan *Client
 	Unregister chan *Client
 	mu         sync.RWMutex
 }
 
-func NewHub() *Hub {
+func NewHub(backplane Backplane, events EventLog) *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan interface{}, 256),
+		broadcast:  make(chan []byte, 256),
+		outbound:   make(chan []byte, 256),
+		notify:     make(chan models.Event, notifyQueueSize),
+		backplane:  backplane,
+		events:     events,
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 	}
 }
 
 func (h *Hub) Run() {
+	incoming, err := h.backplane.Subscribe(context.Background())
+	if err != nil {
+		// Local clients still get this instance's broadcasts
+		log.Printf("Warning: websocket backplane unavailable, broadcasting locally only: %v", err)
+		h.backplane = NewMemoryBackplane()
+		incoming, _ = h.backplane.Subscribe(context.Background())
+	}
+
+	go h.publish()
+	go h.runListeners()
+
 	for {
 		select {
+		case message, ok := <-incoming:
+			if !ok {
+				log.Println("Warning: websocket backplane subscription closed")
+				incoming = nil
+				continue
+			}
+			h.deliver(message)
+
 		case client := <-h.Register:
 			h.mu.Lock()
 			h.clients[client] = true
@@ -53,46 +103,125 @@ func (h *Hub) Run() {
 		case client := <-h.Unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
+				h.drop(client)
 				log.Printf("Client disconnected. Total clients: %d", len(h.clients))
 			}
 			h.mu.Unlock()
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
-			clients := make([]*Client, 0, len(h.clients))
-			for client := range h.clients {
-				clients = append(clients, client)
-			}
-			h.mu.RUnlock()
-
-			// Send to clients without holding the lock
-			for _, client := range clients {
-				select {
-				case client.send <- message:
-				default:
-					// Client's send channel is full, close it
-					h.mu.Lock()
-					if _, ok := h.clients[client]; ok {
-						delete(h.clients, client)
-						close(client.send)
-					}
-					h.mu.Unlock()
-				}
+			h.deliver(message)
+		}
+	}
+}
+
+// deliver sends message to the clients connected to this instance that
+// subscribed to one of its topics
+func (h *Hub) deliver(message []byte) {
+	topics := topicsOf(message)
+
+	var full []*Client
+	h.mu.RLock()
+	for client := range h.clients {
+		if !client.offer(message, topics) {
+			full = append(full, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	// Client's send channel is full, close it
+	for _, client := range full {
+		h.mu.Lock()
+		if _, ok := h.clients[client]; ok {
+			h.drop(client)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// drop removes a client and closes its send channel. The caller holds h.mu.
+func (h *Hub) drop(client *Client) {
+	delete(h.clients, client)
+	client.closed = true
+	close(client.send)
+}
+
+// publish hands queued broadcasts to the backplane. If the backplane fails the
+// message still reaches this instance's clients.
+func (h *Hub) publish() {
+	for message := range h.outbound {
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		if event, err := h.events.Append(ctx, message); err != nil {
+			log.Printf("Warning: %v, broadcasting without a sequence id", err)
+		} else {
+			message = event.Message
+		}
+		err := h.backplane.Publish(ctx, message)
+		cancel()
+
+		if err != nil {
+			log.Printf("Warning: %v, delivering locally only", err)
+			select {
+			case h.broadcast <- message:
+			default:
+				log.Println("Warning: broadcast channel full, message dropped")
 			}
 		}
 	}
 }
 
-func (h *Hub) BroadcastPaymentStatus(data interface{}) {
+// OnBroadcast calls listener with every event broadcast from this instance,
+// e.g. to notify webhooks exactly once per event. Listeners run in order on
+// one goroutine, away from the broadcaster, and must be added before the
+// first broadcast.
+func (h *Hub) OnBroadcast(listener func(models.Event)) {
+	h.listeners = append(h.listeners, listener)
+}
+
+func (h *Hub) runListeners() {
+	for event := range h.notify {
+		h.callListeners(event)
+	}
+}
+
+func (h *Hub) callListeners(event models.Event) {
+	for _, listener := range h.listeners {
+		listener(event)
+	}
+}
+
+// BroadcastPaymentStatus sends event to the clients of every instance
+func (h *Hub) BroadcastPaymentStatus(event models.Event) {
+	if len(h.listeners) > 0 {
+		h.enqueueNotification(event)
+	}
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", event.Type, err)
+		return
+	}
+
 	select {
-	case h.broadcast <- data:
+	case h.outbound <- message:
 	default:
 		log.Println("Warning: broadcast channel full, message dropped")
 	}
 }
 
+// enqueueNotification hands event to the listeners. When they are behind,
+// the caller waits up to notifyTimeout for room and then drops the event.
+func (h *Hub) enqueueNotification(event models.Event) {
+	timer := time.NewTimer(notifyTimeout)
+	defer timer.Stop()
+
+	select {
+	case h.notify <- event:
+	case <-timer.C:
+		dropped := h.dropped.Add(1)
+		log.Printf("Warning: listener queue full, dropped %s event %s (%d dropped since start)", event.Type, event.ID, dropped)
+	}
+}
+
 // ReadPump pumps messages from the websocket connection to the hub
 func (c *Client) ReadPump() {
 	defer func() {
@@ -100,6 +229,7 @@ func (c *Client) ReadPump() {
 		c.conn.Close()
 	}()
 
+	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -107,15 +237,205 @@ func (c *Client) ReadPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
-		// We don't process incoming messages, just use them to keep connection alive
+		c.handleMessage(message)
+	}
+}
+
+// handleMessage applies a subscribe or unsubscribe request from the client
+func (c *Client) handleMessage(message []byte) {
+	var req subscriptionRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.reply(subscriptionReply{Type: "error", Error: "invalid message"})
+		return
+	}
+
+	if req.Action == "resume" {
+		c.Resume(req.LastSeq)
+		return
+	}
+
+	if req.Action != "subscribe" && req.Action != "unsubscribe" {
+		c.reply(subscriptionReply{Type: "error", Error: "action must be subscribe, unsubscribe or resume"})
+		return
+	}
+
+	topics, err := req.topics()
+	if err != nil {
+		c.reply(subscriptionReply{Type: "error", Error: err.Error()})
+		return
+	}
+
+	if req.Action == "subscribe" {
+		err = c.Subscribe(topics)
+	} else {
+		c.unsubscribe(topics)
+	}
+	if err != nil {
+		c.reply(subscriptionReply{Type: "error", Error: err.Error()})
+		return
+	}
+
+	c.reply(subscriptionReply{Type: req.Action + "d", Subscriptions: c.Subscriptions()})
+}
+
+// Subscribe adds topics, provided the client's claims allow every one of them
+func (c *Client) Subscribe(topics []Topic) error {
+	for _, topic := range topics {
+		if !c.claims.Allows(topic) {
+			return fmt.Errorf("not permitted to subscribe to %s %s", topic.Key, topic.Value)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, topic := range topics {
+		c.subscriptions[topic] = true
+	}
+	return nil
+}
+
+func (c *Client) unsubscribe(topics []Topic) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, topic := range topics {
+		delete(c.subscriptions, topic)
+	}
+}
+
+// reply queues a message for this client only
+func (c *Client) reply(message interface{}) {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- message:
+	default:
+	}
+}
+
+// offer queues a broadcast if the client subscribed to one of its topics. It
+// returns false if the client cannot keep up. The caller holds the hub lock.
+func (c *Client) offer(message []byte, topics []Topic) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.subscribed(topics) {
+		return true
+	}
+
+	if c.replaying {
+		if len(c.pending) >= cap(c.send) {
+			return false
+		}
+		c.pending = append(c.pending, message)
+		return true
+	}
+
+	select {
+	case c.send <- json.RawMessage(message):
+		return true
+	default:
+		return false
+	}
+}
+
+// Resume sends the subscribed events after seq that the client missed, then
+// the live broadcasts that arrived meanwhile
+func (c *Client) Resume(seq uint64) {
+	c.mu.Lock()
+	c.replaying = true
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	events, err := c.hub.events.Since(ctx, seq)
+	cancel()
+
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.replaying = false
+	pending := c.pending
+	c.pending = nil
+
+	if c.closed {
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to replay events after %d: %v", seq, err)
+		c.queue(resumeReply{Type: "error", Error: "replay unavailable", LastSeq: seq})
+	} else {
+		reply := resumeReply{Type: "resumed", LastSeq: seq}
+		// Older events were evicted from the log
+		reply.Gap = len(events) > 0 && events[0].Seq > seq+1
+
+		for _, event := range events {
+			if !c.subscribed(topicsOf(event.Message)) {
+				reply.LastSeq = event.Seq
+				continue
+			}
+			// Leave room for the reply; the client can resume again from LastSeq
+			if len(c.send) >= cap(c.send)-1 {
+				reply.Truncated = true
+				break
+			}
+			c.send <- json.RawMessage(event.Message)
+			reply.LastSeq = event.Seq
+		}
+		c.queue(reply)
+		seq = reply.LastSeq
+	}
+
+	for _, message := range pending {
+		// Broadcasts without a sequence id were never in the log
+		if s := seqOf(message); s != 0 && s <= seq {
+			continue
+		}
+		c.queue(json.RawMessage(message))
+	}
+}
+
+// queue sends without blocking; the caller holds the hub lock
+func (c *Client) queue(message interface{}) {
+	select {
+	case c.send <- message:
+	default:
+	}
+}
+
+// subscribed reports whether the client wants a broadcast with these topics.
+// The caller holds c.mu.
+func (c *Client) subscribed(topics []Topic) bool {
+	for _, topic := range topics {
+		if c.subscriptions[topic] {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscriptions returns the topics the client receives broadcasts for
+func (c *Client) Subscriptions() []Topic {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	topics := make([]Topic, 0, len(c.subscriptions))
+	for topic := range c.subscriptions {
+		topics = append(topics, topic)
 	}
+	return topics
 }
 
 // WritePump pumps messages from the hub to the websocket connection
@@ -149,11 +469,13 @@ func (c *Client) WritePump() {
 	}
 }
 
-func NewClient(hub *Hub, conn *websocket.Conn) *Client {
+func NewClient(hub *Hub, conn *websocket.Conn, claims *Claims) *Client {
 	return &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan interface{}, 256),
+		hub:           hub,
+		conn:          conn,
+		claims:        claims,
+		send:          make(chan interface{}, 256),
+		subscriptions: make(map[Topic]bool),
 	}
 }
 
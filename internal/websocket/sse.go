@@ -0,0 +1,101 @@
+// ==========================
+// internal/websocket/sse.go
+// ==========================
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// NewStreamClient creates a hub client that is written to as a stream of
+// Server-Sent Events rather than over a websocket. It cannot send messages,
+// so its subscriptions are fixed when it connects.
+func NewStreamClient(hub *Hub, claims *Claims) *Client {
+	return &Client{
+		hub:           hub,
+		claims:        claims,
+		send:          make(chan interface{}, 256),
+		subscriptions: make(map[Topic]bool),
+	}
+}
+
+// StreamEvents writes the client's messages to w until ctx is done or the hub
+// drops the client. A comment line is sent every pingPeriod so proxies keep
+// the connection open, matching the websocket pings.
+func (c *Client) StreamEvents(ctx context.Context, w http.ResponseWriter) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.leave()
+			return
+
+		case message, ok := <-c.send:
+			if !ok {
+				// The hub closed the channel
+				return
+			}
+			if err := writeSSE(w, message); err != nil {
+				log.Printf("SSE write error: %v", err)
+				c.leave()
+				return
+			}
+			flusher.Flush()
+
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				c.leave()
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// leave unregisters the client and waits for the hub to close send
+func (c *Client) leave() {
+	c.hub.Unregister <- c
+	for range c.send {
+	}
+}
+
+// writeSSE writes one event. Broadcasts with a sequence id carry it as the
+// event id, so the browser sends it back as Last-Event-ID on reconnect.
+func writeSSE(w http.ResponseWriter, message interface{}) error {
+	data, ok := message.(json.RawMessage)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(message); err != nil {
+			return err
+		}
+	}
+
+	if seq := seqOf(data); seq != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", seq); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
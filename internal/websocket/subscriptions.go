@@ -0,0 +1,144 @@
+// ==========================
+// internal/websocket/subscriptions.go
+// ==========================
+package websocket
+
+import (
+	"awesomeProject/internal/models"
+	"awesomeProject/internal/utils"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// Fields of a broadcast a client can subscribe to
+const (
+	TopicCheckoutRequestID        = "checkout_request_id"
+	TopicOriginatorConversationID = "originator_conversation_id"
+	TopicPhoneNumber              = "phone_number"
+	TopicType                     = "type"
+)
+
+var topicKeys = []string{
+	TopicCheckoutRequestID,
+	TopicOriginatorConversationID,
+	TopicPhoneNumber,
+	TopicType,
+}
+
+// Topic is one field value a client wants broadcasts for
+type Topic struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// subscriptionRequest is what clients send, e.g.
+//
+//	{"action": "subscribe", "checkout_request_id": "ws_CO_..."}
+//	{"action": "unsubscribe", "type": "b2c_callback"}
+//	{"action": "resume", "last_seq": 41}
+//
+// Every field that is set adds or removes one topic. Resume replays the
+// subscribed events after last_seq.
+type subscriptionRequest struct {
+	Action                   string `json:"action"`
+	LastSeq                  uint64 `json:"last_seq"`
+	CheckoutRequestID        string `json:"checkout_request_id"`
+	OriginatorConversationID string `json:"originator_conversation_id"`
+	PhoneNumber              string `json:"phone_number"`
+	Type                     string `json:"type"`
+}
+
+func (r *subscriptionRequest) topics() ([]Topic, error) {
+	var topics []Topic
+	if r.CheckoutRequestID != "" {
+		topics = append(topics, Topic{TopicCheckoutRequestID, r.CheckoutRequestID})
+	}
+	if r.OriginatorConversationID != "" {
+		topics = append(topics, Topic{TopicOriginatorConversationID, r.OriginatorConversationID})
+	}
+	if r.PhoneNumber != "" {
+		// Broadcasts carry numbers as 254XXXXXXXXX
+		phone, err := utils.FormatPhoneNumber(r.PhoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		topics = append(topics, Topic{TopicPhoneNumber, phone})
+	}
+	if r.Type != "" {
+		topics = append(topics, Topic{TopicType, r.Type})
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("one of checkout_request_id, originator_conversation_id, phone_number or type is required")
+	}
+	return topics, nil
+}
+
+// TopicsFromQuery reads topics from query parameters named like the topic
+// keys, each of which may repeat, e.g. ?checkout_request_id=a&type=stk_callback
+func TopicsFromQuery(query url.Values) ([]Topic, error) {
+	var topics []Topic
+	for _, key := range topicKeys {
+		for _, value := range query[key] {
+			var req subscriptionRequest
+			switch key {
+			case TopicCheckoutRequestID:
+				req.CheckoutRequestID = value
+			case TopicOriginatorConversationID:
+				req.OriginatorConversationID = value
+			case TopicPhoneNumber:
+				req.PhoneNumber = value
+			case TopicType:
+				req.Type = value
+			}
+
+			topic, err := req.topics()
+			if err != nil {
+				return nil, err
+			}
+			topics = append(topics, topic...)
+		}
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("one of checkout_request_id, originator_conversation_id, phone_number or type is required")
+	}
+	return topics, nil
+}
+
+// subscriptionReply acknowledges a subscription request with the client's
+// current topics
+type subscriptionReply struct {
+	Type          string  `json:"type"`
+	Subscriptions []Topic `json:"subscriptions,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// resumeReply ends a replay. LastSeq is the last event replayed, Truncated
+// means more remain and Gap means some were evicted before they could be sent.
+type resumeReply struct {
+	Type      string `json:"type"`
+	LastSeq   uint64 `json:"last_seq"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Gap       bool   `json:"gap,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// topicsOf returns the subscribable fields present in a broadcast event
+func topicsOf(message []byte) []Topic {
+	var event models.Event
+	if err := json.Unmarshal(message, &event); err != nil {
+		return nil
+	}
+
+	var topics []Topic
+	add := func(key, value string) {
+		if value != "" {
+			topics = append(topics, Topic{key, value})
+		}
+	}
+	add(TopicCheckoutRequestID, event.Correlation.CheckoutRequestID)
+	add(TopicOriginatorConversationID, event.Correlation.OriginatorConversationID)
+	add(TopicPhoneNumber, event.Correlation.PhoneNumber)
+	add(TopicType, string(event.Type))
+	return topics
+}
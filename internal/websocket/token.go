@@ -0,0 +1,114 @@
+// ==========================
+// internal/websocket/token.go
+// ==========================
+package websocket
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid websocket token")
+	ErrExpiredToken = errors.New("websocket token expired")
+)
+
+// Claims limit what a connection may subscribe to. A topic is allowed only
+// if its value is listed under the matching claim.
+type Claims struct {
+	CheckoutRequestIDs        []string  `json:"checkout_request_ids,omitempty"`
+	OriginatorConversationIDs []string  `json:"originator_conversation_ids,omitempty"`
+	PhoneNumbers              []string  `json:"phone_numbers,omitempty"`
+	Types                     []string  `json:"types,omitempty"`
+	ExpiresAt                 time.Time `json:"expires_at"`
+}
+
+// Allows reports whether the claims permit subscribing to topic
+func (c *Claims) Allows(topic Topic) bool {
+	switch topic.Key {
+	case TopicCheckoutRequestID:
+		return slices.Contains(c.CheckoutRequestIDs, topic.Value)
+	case TopicOriginatorConversationID:
+		return slices.Contains(c.OriginatorConversationIDs, topic.Value)
+	case TopicPhoneNumber:
+		return slices.Contains(c.PhoneNumbers, topic.Value)
+	case TopicType:
+		return slices.Contains(c.Types, topic.Value)
+	}
+	return false
+}
+
+// TokenSigner issues and verifies the short-lived tokens clients present when
+// opening a websocket. Tokens are base64url(claims) "." base64url(HMAC-SHA256).
+type TokenSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewTokenSigner signs with secret. Without one a random secret is used, so
+// tokens only work on the instance that issued them.
+func NewTokenSigner(secret string, ttl time.Duration) *TokenSigner {
+	key := []byte(secret)
+	if len(key) == 0 {
+		log.Println("Warning: WS_TOKEN_SECRET not set, websocket tokens are only valid on this instance")
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatal("Failed to generate websocket token secret:", err)
+		}
+	}
+	return &TokenSigner{secret: key, ttl: ttl}
+}
+
+// Issue signs claims, setting their expiry
+func (s *TokenSigner) Issue(claims Claims) (string, time.Time, error) {
+	claims.ExpiresAt = time.Now().Add(s.ttl).UTC()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded), claims.ExpiresAt, nil
+}
+
+// Verify checks token's signature and expiry and returns its claims
+func (s *TokenSigner) Verify(token string) (*Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if time.Now().After(claims.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+func (s *TokenSigner) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
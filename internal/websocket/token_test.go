@@ -0,0 +1,106 @@
+// ==========================
+// internal/websocket/token_test.go
+// ==========================
+package websocket
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenSignerVerifiesIssuedToken(t *testing.T) {
+	signer := NewTokenSigner("secret", time.Minute)
+
+	token, expiresAt, err := signer.Issue(Claims{CheckoutRequestIDs: []string{"ws_CO_1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(expiresAt); until <= 0 || until > time.Minute {
+		t.Errorf("token expires in %v, want within a minute", until)
+	}
+
+	claims, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+	if len(claims.CheckoutRequestIDs) != 1 || claims.CheckoutRequestIDs[0] != "ws_CO_1" {
+		t.Errorf("CheckoutRequestIDs = %v, want [ws_CO_1]", claims.CheckoutRequestIDs)
+	}
+}
+
+func TestTokenSignerRejectsExpiredToken(t *testing.T) {
+	token, _, err := NewTokenSigner("secret", -time.Second).Issue(Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewTokenSigner("secret", time.Minute).Verify(token); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Verify() = %v, want %v", err, ErrExpiredToken)
+	}
+}
+
+func TestTokenSignerRejectsOtherSecret(t *testing.T) {
+	token, _, err := NewTokenSigner("other secret", time.Minute).Issue(Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewTokenSigner("secret", time.Minute).Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestTokenSignerRejectsWidenedClaims(t *testing.T) {
+	signer := NewTokenSigner("secret", time.Minute)
+	narrow, _, err := signer.Issue(Claims{Types: []string{"b2c_callback"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wide, _, err := signer.Issue(Claims{Types: []string{"b2c_callback", "stk_callback"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The wider claims carrying the narrow token's signature
+	wideClaims, _, _ := strings.Cut(wide, ".")
+	_, signature, _ := strings.Cut(narrow, ".")
+
+	for _, token := range []string{wideClaims + "." + signature, wideClaims, ""} {
+		if _, err := signer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Verify(%q) = %v, want %v", token, err, ErrInvalidToken)
+		}
+	}
+}
+
+func TestClaimsAllows(t *testing.T) {
+	claims := &Claims{
+		CheckoutRequestIDs:        []string{"ws_CO_1"},
+		OriginatorConversationIDs: []string{"AG_1"},
+		PhoneNumbers:              []string{"254708374149"},
+		Types:                     []string{"b2c_callback"},
+	}
+
+	for _, topic := range []Topic{
+		{TopicCheckoutRequestID, "ws_CO_1"},
+		{TopicOriginatorConversationID, "AG_1"},
+		{TopicPhoneNumber, "254708374149"},
+		{TopicType, "b2c_callback"},
+	} {
+		if !claims.Allows(topic) {
+			t.Errorf("Allows(%+v) = false, want true", topic)
+		}
+	}
+
+	for _, topic := range []Topic{
+		{TopicCheckoutRequestID, "ws_CO_2"},
+		{TopicOriginatorConversationID, "ws_CO_1"},
+		{TopicType, "stk_callback"},
+		{"receipt_number", "ws_CO_1"},
+	} {
+		if claims.Allows(topic) {
+			t.Errorf("Allows(%+v) = true, want false", topic)
+		}
+	}
+}